@@ -2,4 +2,9 @@ module github.com/navikt/copilot/mcp-onboarding
 
 go 1.25
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/navikt/copilot/libs/problem v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/navikt/copilot/libs/problem => ../../libs/problem
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/navikt/copilot/libs/problem"
 	"github.com/navikt/copilot/mcp-onboarding/internal/discovery"
 )
 
@@ -107,7 +108,8 @@ func (h *MCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	w.Header().Set("Allow", "GET, POST")
+	problem.Write(w, r, problemMethodNotAllowed.New("Only GET and POST are supported"))
 }
 
 func (h *MCPHandler) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
@@ -154,7 +156,7 @@ func (h *MCPHandler) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		problem.Write(w, r, problemInternalError.New("SSE not supported"))
 		return
 	}
 
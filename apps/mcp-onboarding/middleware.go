@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+
+	"github.com/navikt/copilot/libs/problem"
 )
 
 type AuthMiddleware struct {
@@ -51,8 +53,7 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 func (m *AuthMiddleware) sendUnauthorized(w http.ResponseWriter, r *http.Request) {
 	resourceMetadataURL := getBaseURL(r) + "/.well-known/oauth-protected-resource"
 	w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+resourceMetadataURL+`"`)
-	w.WriteHeader(http.StatusUnauthorized)
-	_, _ = w.Write([]byte(`{"error":"unauthorized","message":"Valid Bearer token required"}`))
+	problem.Write(w, r, problemUnauthorized.New("Valid Bearer token required"))
 }
 
 func getBaseURL(r *http.Request) string {
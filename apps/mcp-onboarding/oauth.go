@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/navikt/copilot/libs/problem"
 )
 
 type OAuthServer struct {
@@ -85,7 +87,7 @@ func (s *OAuthServer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
 	codeChallengeMethod := r.URL.Query().Get("code_challenge_method")
 
 	if codeChallengeMethod != "" && codeChallengeMethod != "S256" {
-		http.Error(w, "Only S256 code challenge method supported", http.StatusBadRequest)
+		problem.Write(w, r, problemBadRequest.New("Only S256 code challenge method supported"))
 		return
 	}
 
@@ -124,14 +126,14 @@ func (s *OAuthServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 	if errorParam != "" {
 		errorDesc := r.URL.Query().Get("error_description")
 		slog.Error("github oauth error", "error", errorParam, "description", errorDesc)
-		http.Error(w, fmt.Sprintf("GitHub OAuth error: %s - %s", errorParam, errorDesc), http.StatusBadRequest)
+		problem.Write(w, r, problemBadRequest.New(fmt.Sprintf("GitHub OAuth error: %s - %s", errorParam, errorDesc)))
 		return
 	}
 
 	session, err := s.Store.GetAuthSession(state)
 	if err != nil {
 		slog.Error("invalid state", "error", err)
-		http.Error(w, "Invalid or expired state", http.StatusBadRequest)
+		problem.Write(w, r, problemBadRequest.New("Invalid or expired state"))
 		return
 	}
 	s.Store.DeleteAuthSession(state)
@@ -139,14 +141,14 @@ func (s *OAuthServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 	githubToken, err := s.GitHubClient.ExchangeCode(code)
 	if err != nil {
 		slog.Error("failed to exchange code", "error", err)
-		http.Error(w, "Failed to exchange code with GitHub", http.StatusInternalServerError)
+		problem.Write(w, r, problemInternalError.New("Failed to exchange code with GitHub"))
 		return
 	}
 
 	user, err := s.GitHubClient.GetUser(githubToken.AccessToken)
 	if err != nil {
 		slog.Error("failed to get user", "error", err)
-		http.Error(w, "Failed to get GitHub user", http.StatusInternalServerError)
+		problem.Write(w, r, problemInternalError.New("Failed to get GitHub user"))
 		return
 	}
 
@@ -158,7 +160,7 @@ func (s *OAuthServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 				"user", user.Login,
 				"allowed_org", s.AllowedOrganization,
 			)
-			http.Error(w, fmt.Sprintf("Access denied: You must be a member of the %s organization", s.AllowedOrganization), http.StatusForbidden)
+			problem.Write(w, r, problemForbidden.New(fmt.Sprintf("Access denied: You must be a member of the %s organization", s.AllowedOrganization)))
 			return
 		}
 		slog.Info("user authorized",
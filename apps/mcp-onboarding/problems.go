@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/navikt/copilot/libs/problem"
+)
+
+// The onboarding server has no problem types of its own, so its errors are
+// about:blank problems titled with the status text.
+var (
+	problemBadRequest       = problem.Status(http.StatusBadRequest)
+	problemUnauthorized     = problem.Status(http.StatusUnauthorized)
+	problemForbidden        = problem.Status(http.StatusForbidden)
+	problemMethodNotAllowed = problem.Status(http.StatusMethodNotAllowed)
+	problemInternalError    = problem.Status(http.StatusInternalServerError)
+)
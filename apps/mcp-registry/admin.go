@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/navikt/copilot/libs/problem"
+)
+
+// adminMaxBody bounds the size of admin request bodies.
+const adminMaxBody = 1 << 20
+
+func makePublishHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		publishHandler(w, r, registry)
+	}
+}
+
+func publishHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	var server StaticServerData
+	if err := decodeAdminBody(w, r, &server); err != nil {
+		problem.Write(w, r, problemInvalidRequest.New(err.Error()))
+		return
+	}
+	if server.PublishedAt == "" {
+		server.PublishedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if err := validatePublished(server, registry.config); err != nil {
+		slog.Warn("Rejected server publish", "name", server.Name, "version", server.Version, "error", err)
+		problem.Write(w, r, serverProblem(problemInvalidServer, err.Error(), server.Name, server.Version))
+		return
+	}
+
+	if !applyAdminChange(w, r, registry.Publish(r.Context(), server), "publish", server.Name, server.Version) {
+		return
+	}
+
+	respondChanged(w, registry, server.Name, server.Version, http.StatusCreated)
+}
+
+// validatePublished checks a new entry the way it will be served, with
+// template variables substituted.
+func validatePublished(server StaticServerData, config *Config) error {
+	server.Remotes = slices.Clone(server.Remotes)
+	server.Packages = slices.Clone(server.Packages)
+
+	data := &StaticRegistryData{Servers: []StaticServerData{server}}
+	errs := substituteServerVariables(data, config.templateVariables(), make(map[string]bool))
+	errs = append(errs, validateServerEntry(&data.Servers[0], 0, nil), requireOwner(&data.Servers[0], 0))
+	return errors.Join(errs...)
+}
+
+func makeStatusHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statusHandler(w, r, registry)
+	}
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	name, version := r.PathValue("name"), r.PathValue("version")
+
+	var change statusChange
+	if err := decodeAdminBody(w, r, &change); err != nil {
+		problem.Write(w, r, problemInvalidRequest.New(err.Error()))
+		return
+	}
+	switch change.Status {
+	case StatusActive, StatusDeprecated, StatusDeleted:
+	default:
+		problem.Write(w, r, problemInvalidRequest.New(fmt.Sprintf("'status' must be one of: %s, %s, %s", StatusActive, StatusDeprecated, StatusDeleted)))
+		return
+	}
+
+	if !applyAdminChange(w, r, registry.SetStatus(r.Context(), name, version, change), "set-status:"+change.Status, name, version) {
+		return
+	}
+
+	respondChanged(w, registry, name, version, http.StatusOK)
+}
+
+// respondChanged writes a changed server the way it is now served. A server
+// outside the environment or audience of this registry is stored, but not
+// served, so the response has no body.
+func respondChanged(w http.ResponseWriter, registry *Registry, name, version string, status int) {
+	response, ok := registry.Find(name, version)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+	respondJSON(w, status, response)
+}
+
+func makeDeleteHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deleteHandler(w, r, registry)
+	}
+}
+
+// deleteHandler marks a server version as deleted. Entries are never
+// removed, so clients that pinned the version can see what happened to it.
+func deleteHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	name, version := r.PathValue("name"), r.PathValue("version")
+
+	if !applyAdminChange(w, r, registry.SetStatus(r.Context(), name, version, statusChange{Status: StatusDeleted}), "delete", name, version) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyAdminChange writes the error response for a failed change, or logs a
+// successful one to the audit log. It reports whether the change succeeded.
+func applyAdminChange(w http.ResponseWriter, r *http.Request, err error, action, name, version string) bool {
+	principal := principalFrom(r.Context())
+
+	switch {
+	case err == nil:
+		slog.Info("Admin change", "audit", true, "action", action, "name", name, "version", version, "principal", principal)
+		return true
+	case errors.Is(err, errServerExists):
+		problem.Write(w, r, serverProblem(problemServerExists, fmt.Sprintf("Server '%s' version '%s' already exists", name, version), name, version))
+	case errors.Is(err, errServerNotFound):
+		problem.Write(w, r, serverProblem(problemServerNotFound, fmt.Sprintf("Server '%s' has no version '%s'", name, version), name, version))
+	case errors.Is(err, errSignedAllowlist):
+		problem.Write(w, r, serverProblem(problemSignedAllowlist, "The allowlist is signed and cannot be changed through the admin API", name, version))
+	case errors.Is(err, errInvalidAllowlist):
+		slog.Warn("Rejected admin change", "action", action, "name", name, "version", version, "principal", principal, "error", err)
+		problem.Write(w, r, serverProblem(problemInvalidServer, err.Error(), name, version))
+	default:
+		slog.Error("Admin change failed", "action", action, "name", name, "version", version, "principal", principal, "error", err)
+		problem.Write(w, r, problemInternalError.New("The change could not be stored"))
+	}
+	return false
+}
+
+func decodeAdminBody(w http.ResponseWriter, r *http.Request, v any) error {
+	if mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); mediaType != "" && strings.TrimSpace(mediaType) != "application/json" {
+		return fmt.Errorf("Content-Type must be application/json")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, adminMaxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
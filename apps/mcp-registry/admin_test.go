@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const adminTestAllowlist = `{
+  "servers": [
+    {
+      "name": "io.github.navikt/existing",
+      "description": "Existing server",
+      "owner": "team-test",
+      "version": "1.0.0",
+      "publishedAt": "2025-01-01T00:00:00Z",
+      "remotes": [{"type": "streamable-http", "url": "https://existing.{{domain_internal}}/mcp"}]
+    }
+  ],
+  "mirror": {
+    "include": ["com.atlassian/*"]
+  }
+}`
+
+// newAdminTestServer serves the admin API for a temporary allowlist the way
+// main wires it up, authenticated with a static token.
+func newAdminTestServer(t *testing.T) (*Registry, string, http.Handler) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, adminTestAllowlist, time.Now().Add(-time.Hour))
+
+	config := testConfig()
+	config.AdminToken = "secret"
+	registry := NewRegistry(newFileStore(path, config), config, nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+
+	auth := newTestAuthenticator(t, config)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v0.1/publish", requireAdmin(auth, makePublishHandler(registry)))
+	mux.HandleFunc("PATCH /v0.1/servers/{name}/versions/{version}/status", requireAdmin(auth, makeStatusHandler(registry)))
+	mux.HandleFunc("DELETE /v0.1/servers/{name}/versions/{version}", requireAdmin(auth, makeDeleteHandler(registry)))
+	return registry, path, mux
+}
+
+func adminRequest(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func serverPath(name, version string) string {
+	return "/v0.1/servers/" + url.PathEscape(name) + "/versions/" + version
+}
+
+func TestPublishHandler(t *testing.T) {
+	registry, path, handler := newAdminTestServer(t)
+
+	w := adminRequest(t, handler, http.MethodPost, "/v0.1/publish", `{
+		"name": "io.github.navikt/new-server",
+		"description": "New server",
+		"owner": "team-test",
+		"version": "1.0.0",
+		"remotes": [{"type": "streamable-http", "url": "https://new.{{domain_external}}/mcp"}]
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ServerResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.Server.Remotes[0].URL != "https://new.ekstern.dev.nav.no/mcp" {
+		t.Errorf("expected substituted url in response, got %s", response.Server.Remotes[0].URL)
+	}
+	if response.Meta.Official.PublishedAt.IsZero() {
+		t.Error("expected publishedAt to default to now")
+	}
+
+	if _, ok := registry.Find("io.github.navikt/new-server", "1.0.0"); !ok {
+		t.Error("expected published server to be served without waiting for a reload")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read allowlist: %v", err)
+	}
+	for _, expected := range []string{"https://new.{{domain_external}}/mcp", "https://existing.{{domain_internal}}/mcp", `"com.atlassian/*"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected allowlist file to contain %s, got:\n%s", expected, data)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temporary files to be cleaned up, got %v", entries)
+	}
+}
+
+func TestPublishHandler_Rejected(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		contentType    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"duplicate version", `{"name": "io.github.navikt/existing", "owner": "team-test", "description": "Again", "version": "1.0.0"}`,
+			"application/json", http.StatusConflict, "already exists"},
+		{"invalid entry", `{"name": "invalid name", "owner": "team-test", "description": "", "version": "latest"}`,
+			"application/json", http.StatusBadRequest, "'version' cannot be 'latest'"},
+		{"unknown template variable", `{"name": "io.github.navikt/new", "owner": "team-test", "description": "New", "version": "1.0.0",
+			"remotes": [{"type": "sse", "url": "https://new.{{cluster}}/sse"}]}`,
+			"application/json", http.StatusBadRequest, "{{cluster}} has no value"},
+		{"schema violation", `{"name": "io.github.navikt/new", "owner": "team-test", "description": "New", "version": "1.0.0",
+			"remotes": [{"type": "websocket", "url": "wss://new.example.com"}]}`,
+			"application/json", http.StatusBadRequest, "remotes"},
+		{"unknown field", `{"name": "io.github.navikt/new", "owner": "team-test", "description": "New", "version": "1.0.0", "maintainer": "x"}`,
+			"application/json", http.StatusBadRequest, "unknown field"},
+		{"malformed JSON", `{"name": `, "application/json", http.StatusBadRequest, "invalid request body"},
+		{"wrong content type", `{}`, "text/plain", http.StatusBadRequest, "Content-Type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, path, handler := newAdminTestServer(t)
+			before, _ := os.ReadFile(path)
+
+			req := httptest.NewRequest(http.MethodPost, "/v0.1/publish", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.expectedBody, w.Body.String())
+			}
+
+			after, _ := os.ReadFile(path)
+			if string(before) != string(after) {
+				t.Error("expected allowlist file to be unchanged")
+			}
+			if len(registry.Servers()) != 1 {
+				t.Errorf("expected registry to be unchanged, got %d servers", len(registry.Servers()))
+			}
+		})
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	registry, path, handler := newAdminTestServer(t)
+	target := serverPath("io.github.navikt/existing", "1.0.0") + "/status"
+
+	w := adminRequest(t, handler, http.MethodPatch, target, `{"status": "deprecated"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ServerResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.Meta.Official.Status != StatusDeprecated {
+		t.Errorf("expected deprecated status in response, got %s", response.Meta.Official.Status)
+	}
+
+	server, _ := registry.Find("io.github.navikt/existing", "1.0.0")
+	if server.Meta.Official.Status != StatusDeprecated {
+		t.Errorf("expected registry to serve deprecated status, got %s", server.Meta.Official.Status)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"status": "deprecated"`) || !strings.Contains(string(data), "{{domain_internal}}") {
+		t.Errorf("expected status to be persisted with template variables kept, got:\n%s", data)
+	}
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+	}{
+		{"deprecation details", target, `{"status": "deprecated", "deprecationMessage": "Moved", "replacedBy": "io.github.navikt/other"}`, http.StatusOK},
+		{"deprecation details on active", target, `{"status": "active", "deprecationMessage": "Moved"}`, http.StatusBadRequest},
+		{"unknown status", target, `{"status": "retired"}`, http.StatusBadRequest},
+		{"unknown server", serverPath("io.github.navikt/missing", "1.0.0") + "/status", `{"status": "deprecated"}`, http.StatusNotFound},
+		{"unknown version", serverPath("io.github.navikt/existing", "9.9.9") + "/status", `{"status": "deprecated"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := adminRequest(t, handler, http.MethodPatch, tt.target, tt.body); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteHandler(t *testing.T) {
+	registry, _, handler := newAdminTestServer(t)
+
+	w := adminRequest(t, handler, http.MethodDelete, serverPath("io.github.navikt/existing", "1.0.0"), "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	server, ok := registry.Find("io.github.navikt/existing", "1.0.0")
+	if !ok || server.Meta.Official.Status != StatusDeleted {
+		t.Errorf("expected entry to be kept with status deleted, got %+v", server.Meta.Official)
+	}
+
+	if w := adminRequest(t, handler, http.MethodDelete, serverPath("io.github.navikt/missing", "1.0.0"), ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAdminHandlers_RequireAuth(t *testing.T) {
+	_, path, handler := newAdminTestServer(t)
+	before, _ := os.ReadFile(path)
+
+	req := httptest.NewRequest(http.MethodDelete, serverPath("io.github.navikt/existing", "1.0.0"), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+	if after, _ := os.ReadFile(path); string(before) != string(after) {
+		t.Error("expected unauthenticated request not to change the allowlist")
+	}
+}
+
+func TestFileBackend_ConcurrentPublish(t *testing.T) {
+	_, path, handler := newAdminTestServer(t)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := `{"name": "io.github.navikt/concurrent", "owner": "team-test", "description": "Concurrent", "version": "1.0.` + string(rune('0'+i)) + `"}`
+			if w := adminRequest(t, handler, http.MethodPost, "/v0.1/publish", body); w.Code != http.StatusCreated {
+				t.Errorf("expected status 201, got %d: %s", w.Code, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := validateAllowListFile(path, testConfig())
+	if err != nil {
+		t.Fatalf("expected allowlist to stay valid, got %v", err)
+	}
+	if len(data.Servers) != 11 {
+		t.Errorf("expected every publish to be kept, got %d servers", len(data.Servers))
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/navikt/copilot/libs/problem"
+)
+
+const (
+	// jwksRefreshInterval limits how often an unknown key ID triggers a
+	// refetch of the signing keys, so random tokens cannot hammer the issuer.
+	jwksRefreshInterval = time.Minute
+)
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid token")
+	errForbidden    = errors.New("not a member of an admin group")
+)
+
+// Authenticator checks the bearer token of admin requests. A request is
+// accepted if it carries the static admin token, or a valid OIDC access
+// token from the configured issuer, such as Azure AD.
+type Authenticator struct {
+	token string
+	oidc  *oidcVerifier
+}
+
+// NewAuthenticator returns nil when neither a static token nor an OIDC
+// issuer is configured, in which case the admin API is disabled. OIDC needs
+// the issuer, the JWKS URI and the client ID tokens must be issued for, and
+// at least one admin group, since every token in the tenant can be issued
+// for the application. Setting only some of them is an error, so a typo
+// does not silently turn OIDC off.
+func NewAuthenticator(config *Config) (*Authenticator, error) {
+	auth := &Authenticator{token: config.AdminToken}
+	oidcSettings := map[string]string{
+		"AZURE_OPENID_CONFIG_ISSUER":   config.OIDCIssuer,
+		"AZURE_OPENID_CONFIG_JWKS_URI": config.OIDCJWKSURI,
+		"AZURE_APP_CLIENT_ID":          config.OIDCAudience,
+	}
+	var missing []string
+	for name, value := range oidcSettings {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	if len(missing) > 0 && len(missing) < len(oidcSettings) {
+		return nil, fmt.Errorf("OIDC is partly configured, %s must be set too", strings.Join(missing, ", "))
+	}
+	if len(missing) == 0 {
+		if len(config.AdminGroups) == 0 {
+			return nil, errors.New("ADMIN_GROUPS must be set when OIDC is configured")
+		}
+		auth.oidc = newOIDCVerifier(config.OIDCIssuer, config.OIDCAudience, config.OIDCJWKSURI, config.AdminGroups, nil)
+	}
+	if auth.token == "" && auth.oidc == nil {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// Authenticate returns the principal the request was made by.
+func (a *Authenticator) Authenticate(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", errMissingToken
+	}
+
+	if a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1 {
+		return "admin-token", nil
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		return a.oidc.Verify(r.Context(), token)
+	}
+	return "", errInvalidToken
+}
+
+type principalKey struct{}
+
+// principalFrom returns the principal stored by requireAdmin.
+func principalFrom(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// requireAdmin rejects requests that auth does not accept, and stores the
+// principal in the request context for audit logging.
+func requireAdmin(auth *Authenticator, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		switch {
+		case errors.Is(err, errForbidden):
+			slog.Warn("Admin request forbidden", "method", r.Method, "path", r.URL.Path, "error", err)
+			problem.Write(w, r, problemForbidden.New("The token is not allowed to change the registry"))
+			return
+		case errors.Is(err, errMissingToken):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-registry"`)
+			problem.Write(w, r, problemUnauthorized.New("A bearer token is required"))
+			return
+		case err != nil:
+			slog.Warn("Admin request rejected", "method", r.Method, "path", r.URL.Path, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-registry", error="invalid_token"`)
+			problem.Write(w, r, problemUnauthorized.New("The bearer token is invalid"))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+// oidcVerifier validates RS256 signed JWT access tokens from the issuer, and
+// requires them to be issued for the application to a member of an admin
+// group.
+type oidcVerifier struct {
+	verifier *oidc.IDTokenVerifier
+	keys     *jwksKeySet
+	groups   []string
+}
+
+func newOIDCVerifier(issuer, audience, jwksURI string, groups []string, client *http.Client) *oidcVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	keys := &jwksKeySet{uri: jwksURI, client: client}
+	return &oidcVerifier{
+		verifier: oidc.NewVerifier(issuer, keys, &oidc.Config{ClientID: audience, SupportedSigningAlgs: []string{oidc.RS256}}),
+		keys:     keys,
+		groups:   groups,
+	}
+}
+
+// tokenClaims are the claims of an Azure AD access token the registry reads
+// besides the standard ones go-oidc checks.
+type tokenClaims struct {
+	Groups            []string `json:"groups"`
+	PreferredUsername string   `json:"preferred_username"`
+	AzpName           string   `json:"azp_name"`
+}
+
+// Verify checks the token's signature and claims and returns its principal.
+func (v *oidcVerifier) Verify(ctx context.Context, token string) (string, error) {
+	idToken, err := v.verifier.Verify(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+
+	var claims tokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return "", fmt.Errorf("%w: invalid claims: %v", errInvalidToken, err)
+	}
+	if !slices.ContainsFunc(claims.Groups, func(g string) bool { return slices.Contains(v.groups, g) }) {
+		return "", errForbidden
+	}
+
+	for _, principal := range []string{claims.PreferredUsername, claims.AzpName, idToken.Subject} {
+		if principal != "" {
+			return principal, nil
+		}
+	}
+	return "", fmt.Errorf("%w: token has no subject", errInvalidToken)
+}
+
+// jwksKeySet verifies token signatures with the keys published at the
+// issuer's JWKS endpoint. Unlike oidc.RemoteKeySet, it refetches the keys
+// for an unknown key ID at most every jwksRefreshInterval.
+type jwksKeySet struct {
+	uri    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// VerifySignature implements oidc.KeySet.
+func (s *jwksKeySet) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256})
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT: %v", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("JWT must have exactly one signature")
+	}
+
+	kid := jws.Signatures[0].Header.KeyID
+	key, err := s.key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	return jws.Verify(key)
+}
+
+// key returns the signing key with the given ID, refetching the key set
+// when the ID is unknown, for example after the issuer rotated its keys.
+func (s *jwksKeySet) key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keys := s.keys.Key(kid); len(keys) > 0 {
+		return &keys[0], nil
+	}
+	if time.Since(s.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key '%s'", kid)
+	}
+
+	keys, err := s.fetch(ctx)
+	s.fetchedAt = time.Now()
+	if err != nil {
+		slog.Error("Failed to fetch OIDC signing keys", "jwks_uri", s.uri, "error", err)
+		return nil, errors.New("cannot fetch signing keys")
+	}
+	s.keys = keys
+
+	if keys := s.keys.Key(kid); len(keys) > 0 {
+		return &keys[0], nil
+	}
+	return nil, fmt.Errorf("unknown signing key '%s'", kid)
+}
+
+func (s *jwksKeySet) fetch(ctx context.Context) (jose.JSONWebKeySet, error) {
+	var set jose.JSONWebKeySet
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.uri, nil)
+	if err != nil {
+		return set, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return set, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return set, fmt.Errorf("JWKS endpoint returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return set, fmt.Errorf("invalid JWKS: %v", err)
+	}
+	return set, nil
+}
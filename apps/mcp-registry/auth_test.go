@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://login.microsoftonline.com/tenant/v2.0"
+	testAudience = "registry-client-id"
+)
+
+// testIssuerKeys serves the public key of a freshly generated RSA key as a
+// JWKS, and counts how often the key set is fetched.
+type testIssuerKeys struct {
+	key     *rsa.PrivateKey
+	kid     string
+	fetches atomic.Int32
+	server  *httptest.Server
+}
+
+func newTestIssuerKeys(t *testing.T) *testIssuerKeys {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	keys := &testIssuerKeys{key: key, kid: "key-1"}
+	keys.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys.fetches.Add(1)
+		respondJSON(w, http.StatusOK, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": keys.kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(keys.server.Close)
+	return keys
+}
+
+func (k *testIssuerKeys) sign(t *testing.T, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"sub":                "subject-id",
+		"preferred_username": "ola.nordmann@nav.no",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nbf":                time.Now().Add(-time.Minute).Unix(),
+		"groups":             []string{"platform-admins"},
+	}
+}
+
+func authTestConfig(jwksURI string) *Config {
+	config := testConfig()
+	config.AdminToken = "static-secret"
+	config.OIDCIssuer = testIssuer
+	config.OIDCAudience = testAudience
+	config.OIDCJWKSURI = jwksURI
+	config.AdminGroups = []string{"platform-admins"}
+	return config
+}
+
+// newTestAuthenticator returns the authenticator for config, failing the
+// test if config is invalid.
+func newTestAuthenticator(t *testing.T, config *Config) *Authenticator {
+	t.Helper()
+	auth, err := NewAuthenticator(config)
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	return auth
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	if newTestAuthenticator(t, testConfig()) != nil {
+		t.Error("expected admin API to be disabled without token or OIDC")
+	}
+
+	config := testConfig()
+	config.OIDCIssuer = testIssuer
+	config.OIDCJWKSURI = "https://example.com/keys"
+	config.AdminToken = "static-secret"
+	if _, err := NewAuthenticator(config); err == nil || !strings.Contains(err.Error(), "AZURE_APP_CLIENT_ID") {
+		t.Errorf("expected OIDC without a client ID to be refused, got %v", err)
+	}
+	config.AdminToken = ""
+
+	config.OIDCAudience = testAudience
+	if _, err := NewAuthenticator(config); err == nil || !strings.Contains(err.Error(), "ADMIN_GROUPS") {
+		t.Errorf("expected OIDC without admin groups to be refused, got %v", err)
+	}
+	config.AdminToken = "static-secret"
+	if _, err := NewAuthenticator(config); err == nil {
+		t.Error("expected OIDC without admin groups to be refused with a static token too")
+	}
+
+	config.AdminGroups = []string{"platform-admins"}
+	if auth := newTestAuthenticator(t, config); auth == nil || auth.oidc == nil {
+		t.Error("expected OIDC to be enabled")
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	auth := newTestAuthenticator(t, authTestConfig(keys.server.URL))
+
+	with := func(change func(map[string]any)) string {
+		claims := validClaims()
+		change(claims)
+		return keys.sign(t, keys.kid, claims)
+	}
+
+	tests := []struct {
+		name              string
+		authorization     string
+		expectedStatus    int
+		expectedPrincipal string
+	}{
+		{"static token", "Bearer static-secret", http.StatusOK, "admin-token"},
+		{"scheme is case-insensitive", "bearer static-secret", http.StatusOK, "admin-token"},
+		{"valid OIDC token", "Bearer " + keys.sign(t, keys.kid, validClaims()), http.StatusOK, "ola.nordmann@nav.no"},
+		{"audience list", "Bearer " + with(func(c map[string]any) { c["aud"] = []string{"other", testAudience} }), http.StatusOK, "ola.nordmann@nav.no"},
+		{"app token principal", "Bearer " + with(func(c map[string]any) { delete(c, "preferred_username"); c["azp_name"] = "dev-gcp:nais:deployer" }), http.StatusOK, "dev-gcp:nais:deployer"},
+		{"missing header", "", http.StatusUnauthorized, ""},
+		{"basic auth", "Basic YWRtaW46YWRtaW4=", http.StatusUnauthorized, ""},
+		{"wrong static token", "Bearer wrong", http.StatusUnauthorized, ""},
+		{"wrong issuer", "Bearer " + with(func(c map[string]any) { c["iss"] = "https://evil.example.com" }), http.StatusUnauthorized, ""},
+		{"wrong audience", "Bearer " + with(func(c map[string]any) { c["aud"] = "other-app" }), http.StatusUnauthorized, ""},
+		{"expired", "Bearer " + with(func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), http.StatusUnauthorized, ""},
+		{"not yet valid", "Bearer " + with(func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() }), http.StatusUnauthorized, ""},
+		{"missing exp", "Bearer " + with(func(c map[string]any) { delete(c, "exp") }), http.StatusUnauthorized, ""},
+		{"unknown key", "Bearer " + keys.sign(t, "key-2", validClaims()), http.StatusUnauthorized, ""},
+		{"tampered payload", "Bearer " + keys.sign(t, keys.kid, validClaims())[:20] + "x" + keys.sign(t, keys.kid, validClaims())[21:], http.StatusUnauthorized, ""},
+		{"not in admin group", "Bearer " + with(func(c map[string]any) { c["groups"] = []string{"developers"} }), http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal string
+			handler := requireAdmin(auth, func(w http.ResponseWriter, r *http.Request) {
+				principal = principalFrom(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v0.1/publish", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if principal != tt.expectedPrincipal {
+				t.Errorf("expected principal %q, got %q", tt.expectedPrincipal, principal)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestOIDCVerifier_KeyRefresh(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	verifier := newOIDCVerifier(testIssuer, testAudience, keys.server.URL, []string{"platform-admins"}, nil)
+
+	for range 3 {
+		if _, err := verifier.Verify(t.Context(), keys.sign(t, keys.kid, validClaims())); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if fetches := keys.fetches.Load(); fetches != 1 {
+		t.Errorf("expected keys to be cached, got %d fetches", fetches)
+	}
+
+	for range 3 {
+		if _, err := verifier.Verify(t.Context(), keys.sign(t, "unknown", validClaims())); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	}
+	if fetches := keys.fetches.Load(); fetches != 1 {
+		t.Errorf("expected unknown keys not to refetch within %s, got %d fetches", jwksRefreshInterval, fetches)
+	}
+
+	verifier.keys.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	keys.kid = "rotated"
+	if _, err := verifier.Verify(t.Context(), keys.sign(t, "rotated", validClaims())); err != nil {
+		t.Errorf("expected rotated key to be fetched, got %v", err)
+	}
+	if fetches := keys.fetches.Load(); fetches != 2 {
+		t.Errorf("expected 2 fetches after rotation, got %d", fetches)
+	}
+}
+
+func TestOIDCVerifier_RejectsOtherAlgorithms(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	verifier := newOIDCVerifier(testIssuer, testAudience, keys.server.URL, []string{"platform-admins"}, nil)
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"key-1"}`))
+	payload, _ := json.Marshal(validClaims())
+	token := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+
+	if _, err := verifier.Verify(t.Context(), token); err == nil {
+		t.Error("expected unsigned token to be rejected")
+	}
+}
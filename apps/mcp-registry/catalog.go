@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/navikt/copilot/libs/problem"
+)
+
+// buildVersion is the version of this build, set at link time with
+// -ldflags "-X main.buildVersion=...".
+var buildVersion = "dev"
+
+//go:embed templates/catalog.html
+var catalogHTML string
+
+var catalogTemplate = template.Must(template.New("catalog").Funcs(template.FuncMap{
+	"health":     remoteHealth,
+	"pathEscape": url.PathEscape,
+}).Parse(catalogHTML))
+
+// catalogPage is the data the HTML catalog is rendered from.
+type catalogPage struct {
+	Nonce       string
+	Version     string
+	Environment string
+	UpdatedAt   time.Time
+	Servers     []catalogServer
+}
+
+// catalogServer is a server with its latest version, every version that is
+// not deleted, and the VS Code mcp.json and install link for the latest
+// version.
+type catalogServer struct {
+	Latest     ServerResponse
+	Versions   []ServerResponse
+	Config     string
+	InstallURL template.URL
+}
+
+func makeRootHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rootHandler(w, r, registry)
+	}
+}
+
+// rootHandler serves the HTML catalog to browsers and the service index to
+// everyone else.
+func rootHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	w.Header().Add("Vary", "Accept")
+	if prefersHTML(r.Header.Get("Accept")) {
+		catalogHandler(w, r, registry)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service":     "Nav MCP Registry",
+		"version":     buildVersion,
+		"description": "Nav internal MCP server registry providing approved servers for GitHub Copilot",
+		"endpoints": map[string]string{
+			"servers":         "/v0.1/servers",
+			"server_versions": "/v0.1/servers/{serverName}/versions",
+			"server_version":  "/v0.1/servers/{serverName}/versions/{version}",
+			"signature":       "/v0.1/signature",
+			"health":          "/health",
+			"ready":           "/ready",
+			"metrics":         "/metrics",
+		},
+	})
+}
+
+func catalogHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	data := newCatalogPage(registry.Snapshot(), registry.config)
+	data.Nonce = rand.Text()
+
+	var page bytes.Buffer
+	if err := catalogTemplate.Execute(&page, data); err != nil {
+		slog.Error("Failed to render catalog", "error", err)
+		problem.Write(w, r, problemInternalError.New("The catalog could not be rendered"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", catalogContentSecurityPolicy(data.Nonce))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(page.Bytes())
+}
+
+// catalogContentSecurityPolicy only allows the inline style and script of
+// the catalog, which carry nonce.
+func catalogContentSecurityPolicy(nonce string) string {
+	return fmt.Sprintf("default-src 'none'; style-src 'nonce-%[1]s'; script-src 'nonce-%[1]s'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'", nonce)
+}
+
+func newCatalogPage(snapshot *registrySnapshot, config *Config) catalogPage {
+	page := catalogPage{
+		Version:     buildVersion,
+		Environment: config.Environment,
+	}
+	if snapshot == nil {
+		return page
+	}
+	page.UpdatedAt = snapshot.updatedAt
+
+	for _, s := range snapshot.servers {
+		if s.Meta.Official.Status == StatusDeleted {
+			continue
+		}
+		if n := len(page.Servers); n == 0 || page.Servers[n-1].Latest.Server.Name != s.Server.Name {
+			latest, _ := snapshot.Find(s.Server.Name, VersionLatest)
+			installURL, _ := vscodeInstallURL("vscode", &latest.Server)
+			page.Servers = append(page.Servers, catalogServer{
+				Latest:     latest,
+				Config:     vscodeSnippet(&latest.Server),
+				InstallURL: template.URL(installURL), // #nosec G203 -- built from a vscode: scheme and an escaped query
+			})
+		}
+		current := &page.Servers[len(page.Servers)-1]
+		current.Versions = append(current.Versions, s)
+	}
+
+	return page
+}
+
+// vscodeSnippet returns the indented mcp.json for a server, or "" if it
+// cannot be configured.
+func vscodeSnippet(s *ServerJSON) string {
+	config, ok := newVSCodeConfig(s)
+	if !ok {
+		return ""
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// remoteHealth returns the last probe result for a remote of s, or nil.
+func remoteHealth(s ServerResponse, remoteURL string) *RemoteHealth {
+	if s.Meta.Nav == nil {
+		return nil
+	}
+	for i := range s.Meta.Nav.Health {
+		if s.Meta.Nav.Health[i].URL == remoteURL {
+			return &s.Meta.Nav.Health[i]
+		}
+	}
+	return nil
+}
+
+// prefersHTML reports whether an Accept header ranks text/html above
+// application/json. Clients that accept both equally, such as curl with
+// */*, get JSON.
+func prefersHTML(accept string) bool {
+	return acceptQuality(accept, "text/html") > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the quality an Accept header gives a media type,
+// using the most specific matching range.
+func acceptQuality(accept, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	for part := range strings.SplitSeq(accept, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		var s int
+		switch name {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality, specificity = q, s
+	}
+
+	return quality
+}
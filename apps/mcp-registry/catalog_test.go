@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const catalogTestAllowlist = `{"servers": [
+	{"name": "io.github.test/remote", "description": "Remote <b>server</b>", "version": "2.0.0",
+		"owner": "team-test", "contact": "#team-test",
+		"review": {"risk": "high", "dataClassification": "internal", "reviewedAt": "2025-06-01", "reviewer": "appsec", "scopes": ["repo:read"]},
+		"remotes": [{"type": "streamable-http", "url": "https://remote.{{domain_internal}}/mcp"}]},
+	{"name": "io.github.test/remote", "owner": "team-test", "description": "Remote server", "version": "1.0.0", "status": "deprecated",
+		"deprecationMessage": "Upgrade to 2.0.0"},
+	{"name": "io.github.test/remote", "description": "Remote server", "version": "0.1.0", "status": "deleted"},
+	{"name": "io.github.test/package", "owner": "team-test", "description": "Package server", "version": "1.0.0",
+		"packages": [{"registryType": "npm", "identifier": "@navikt/package-mcp", "version": "1.0.0", "transport": {"type": "stdio"}}]},
+	{"name": "io.github.test/gone", "description": "Gone server", "version": "1.0.0", "status": "deleted"}
+]}`
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/html", true},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"application/json, text/html;q=0.9", false},
+		{"text/*, application/json;q=0.5", true},
+		{"text/html;q=0, */*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := prefersHTML(tt.accept); got != tt.expected {
+				t.Errorf("expected prefersHTML(%q) = %v, got %v", tt.accept, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRootHandler_Negotiation(t *testing.T) {
+	registry := loadTestRegistry(t, catalogTestAllowlist, time.Now())
+	buildVersion = "2025.06.01-abc1234"
+	t.Cleanup(func() { buildVersion = "dev" })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	rootHandler(w, req, registry)
+
+	var index map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &index); err != nil {
+		t.Fatalf("expected JSON index, got %v: %s", err, w.Body.String())
+	}
+	if index["version"] != "2025.06.01-abc1234" {
+		t.Errorf("expected build version in index, got %v", index["version"])
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", vary)
+	}
+
+	registry.config.CORSAllowedOrigins = []string{"https://portal.nav.no"}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Origin", "https://portal.nav.no")
+	w = httptest.NewRecorder()
+	serveTestRequest(w, req, registry)
+
+	if vary := w.Header().Values("Vary"); !slices.Equal(vary, []string{"Origin", "Accept"}) {
+		t.Errorf("expected Vary: Origin and Accept, got %q", vary)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	w = httptest.NewRecorder()
+	rootHandler(w, req, registry)
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Fatalf("expected HTML catalog, got %s", contentType)
+	}
+	body := w.Body.String()
+	csp := w.Header().Get("Content-Security-Policy")
+	nonce, _, _ := strings.Cut(strings.TrimPrefix(csp, "default-src 'none'; style-src 'nonce-"), "'")
+	if nonce == "" || !strings.Contains(body, `<style nonce="`+nonce+`">`) || !strings.Contains(body, `<script nonce="`+nonce+`">`) {
+		t.Errorf("expected the inline style and script to carry the nonce of %q", csp)
+	}
+	for _, expected := range []string{
+		"io.github.test/remote",
+		"Remote &lt;b&gt;server&lt;/b&gt;",
+		`<span class="badge active">active</span>`,
+		`<span class="badge deprecated">deprecated</span>`,
+		"https://remote.intern.dev.nav.no/mcp",
+		`href="/v0.1/servers/io.github.test%2Fremote/versions/1.0.0"`,
+		"@navikt/package-mcp@1.0.0",
+		"Version 2025.06.01-abc1234",
+		"Copy mcp.json",
+		`Owned by <a href="https://github.com/orgs/navikt/teams/team-test">team-test</a> · Contact #team-test`,
+		"high risk · internal data · reviewed 2025-06-01 by appsec · scopes <code>repo:read</code>",
+		`<a href="vscode:mcp/install?%7B%22name%22%3A%22remote%22`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected catalog to contain %q", expected)
+		}
+	}
+	for _, unexpected := range []string{"0.1.0", "io.github.test/gone", "<b>server</b>"} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("expected catalog not to contain %q", unexpected)
+		}
+	}
+}
+
+func TestNewCatalogPage(t *testing.T) {
+	registry := loadTestRegistry(t, catalogTestAllowlist, time.Now())
+
+	page := newCatalogPage(registry.Snapshot(), testConfig())
+
+	if len(page.Servers) != 2 {
+		t.Fatalf("expected 2 servers with versions that are not deleted, got %d", len(page.Servers))
+	}
+	remote := page.Servers[1]
+	if remote.Latest.Server.Version != "2.0.0" || len(remote.Versions) != 2 {
+		t.Errorf("expected latest 2.0.0 and 2 versions, got %s and %d", remote.Latest.Server.Version, len(remote.Versions))
+	}
+	if !strings.Contains(remote.Config, `"type": "http"`) || !strings.Contains(remote.Config, `"remote"`) {
+		t.Errorf("expected VS Code config for the remote, got %s", remote.Config)
+	}
+
+	if empty := newCatalogPage(nil, testConfig()); len(empty.Servers) != 0 {
+		t.Errorf("expected no servers before the first load, got %d", len(empty.Servers))
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+const (
+	outputFormatText   = "text"
+	outputFormatJSON   = "json"
+	outputFormatGitHub = "github"
+)
+
+// fileResult is the validation outcome for a single allowlist file.
+type fileResult struct {
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// runValidate implements the validate subcommand. It checks every allowlist
+// file given in args, reports all problems in the requested format and
+// returns the process exit code.
+func runValidate(args []string, config *Config, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mcp-registry validate [--format text|json|github] [--strict] [file ...]")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", outputFormatText, "output format: text, json or github")
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	switch *format {
+	case outputFormatText, outputFormatJSON, outputFormatGitHub:
+	default:
+		fmt.Fprintf(stderr, "unknown format '%s', must be one of: %s, %s, %s\n",
+			*format, outputFormatText, outputFormatJSON, outputFormatGitHub)
+		return exitUsage
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{config.AllowlistPath}
+	}
+
+	results := make([]fileResult, 0, len(files))
+	exitCode := exitValid
+	for _, file := range files {
+		report := checkAllowListFile(file, config)
+		result := fileResult{
+			File:     file,
+			Errors:   errorStrings(report.errors),
+			Warnings: errorStrings(report.warnings),
+		}
+		result.Valid = len(result.Errors) == 0 && (!*strict || len(result.Warnings) == 0)
+		if !result.Valid {
+			exitCode = exitInvalid
+		}
+		results = append(results, result)
+	}
+
+	switch *format {
+	case outputFormatJSON:
+		writeJSONResults(stdout, results)
+	case outputFormatGitHub:
+		writeGitHubResults(stdout, results, *strict)
+	default:
+		writeTextResults(stdout, results)
+	}
+
+	return exitCode
+}
+
+func errorStrings(errs []error) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func writeTextResults(w io.Writer, results []fileResult) {
+	for _, result := range results {
+		for _, msg := range result.Errors {
+			fmt.Fprintf(w, "%s: error: %s\n", result.File, msg)
+		}
+		for _, msg := range result.Warnings {
+			fmt.Fprintf(w, "%s: warning: %s\n", result.File, msg)
+		}
+		if result.Valid {
+			fmt.Fprintf(w, "%s: ok (%d warnings)\n", result.File, len(result.Warnings))
+		} else {
+			fmt.Fprintf(w, "%s: failed (%d errors, %d warnings)\n", result.File, len(result.Errors), len(result.Warnings))
+		}
+	}
+}
+
+func writeJSONResults(w io.Writer, results []fileResult) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(struct {
+		Files []fileResult `json:"files"`
+	}{results})
+}
+
+// writeGitHubResults writes GitHub Actions workflow commands, which show up
+// as annotations on the pull request. In strict mode warnings are reported
+// as errors, since they fail the check.
+func writeGitHubResults(w io.Writer, results []fileResult, strict bool) {
+	warningLevel := "warning"
+	if strict {
+		warningLevel = "error"
+	}
+	for _, result := range results {
+		for _, msg := range result.Errors {
+			fmt.Fprintf(w, "::error file=%s,title=Invalid allowlist::%s\n", escapeGitHubProperty(result.File), escapeGitHubData(msg))
+		}
+		for _, msg := range result.Warnings {
+			fmt.Fprintf(w, "::%s file=%s,title=Allowlist warning::%s\n", warningLevel, escapeGitHubProperty(result.File), escapeGitHubData(msg))
+		}
+	}
+}
+
+var githubDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+var githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func escapeGitHubData(s string) string {
+	return githubDataEscaper.Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return githubPropertyEscaper.Replace(s)
+}
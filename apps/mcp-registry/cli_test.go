@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const cliValidAllowlist = `{
+  "servers": [
+    {
+      "name": "io.github.navikt/github-mcp",
+      "description": "GitHub MCP server",
+      "version": "1.0.0",
+      "publishedAt": "2025-01-01T00:00:00Z",
+      "owner": "copilot",
+      "remotes": [{"type": "streamable-http", "url": "https://github-mcp.{{domain_external}}/mcp"}]
+    }
+  ]
+}`
+
+const cliWarningAllowlist = `{
+  "servers": [
+    {
+      "name": "io.github.navikt/github-mcp",
+      "description": "GitHub MCP server",
+      "version": "1.0.0",
+      "owner": "copilot",
+      "remotes": [{"type": "streamable-http", "url": "https://github-mcp.{{domain_external}}/mcp"}]
+    }
+  ]
+}`
+
+const cliInvalidAllowlist = `{
+  "servers": [
+    {
+      "name": "invalid name",
+      "description": "",
+      "version": "latest",
+      "publishedAt": "2025-01-01T00:00:00Z",
+      "remotes": [{"type": "streamable-http", "url": "https://github-mcp.{{domain_unknown}}/mcp"}]
+    }
+  ]
+}`
+
+func writeCLIAllowlist(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write allowlist: %v", err)
+	}
+	return path
+}
+
+func TestRunValidate(t *testing.T) {
+	valid := writeCLIAllowlist(t, cliValidAllowlist)
+	warning := writeCLIAllowlist(t, cliWarningAllowlist)
+	invalid := writeCLIAllowlist(t, cliInvalidAllowlist)
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedCode   int
+		expectedOutput []string
+	}{
+		{"valid file", []string{valid}, exitValid, []string{"ok (0 warnings)"}},
+		{"warnings pass by default", []string{warning}, exitValid, []string{
+			"warning: server[0]: 'publishedAt' is missing",
+			"ok (1 warnings)",
+		}},
+		{"warnings fail in strict mode", []string{"--strict", warning}, exitInvalid, []string{"failed (0 errors, 1 warnings)"}},
+		{"reports every error", []string{invalid}, exitInvalid, []string{
+			"error: /servers/0/name",
+			"error: /servers/0/description",
+			"error: server[0]: 'version' cannot be 'latest'",
+			"error: server[0].remotes[0]: template variable {{domain_unknown}} has no value",
+		}},
+		{"any invalid file fails", []string{valid, invalid}, exitInvalid, []string{valid + ": ok", invalid + ": failed"}},
+		{"missing file", []string{filepath.Join(t.TempDir(), "missing.json")}, exitInvalid, []string{"cannot read"}},
+		{"unknown format", []string{"--format", "xml", valid}, exitUsage, nil},
+		{"unknown flag", []string{"--verbose", valid}, exitUsage, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runValidate(tt.args, testConfig(), &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("expected exit code %d, got %d\nstdout: %s\nstderr: %s", tt.expectedCode, code, stdout.String(), stderr.String())
+			}
+			for _, expected := range tt.expectedOutput {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunValidate_DefaultFile(t *testing.T) {
+	config := testConfig()
+	config.AllowlistPath = writeCLIAllowlist(t, cliValidAllowlist)
+
+	var stdout, stderr bytes.Buffer
+	if code := runValidate(nil, config, &stdout, &stderr); code != exitValid {
+		t.Fatalf("expected exit code %d, got %d: %s", exitValid, code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), config.AllowlistPath) {
+		t.Errorf("expected output to mention %s, got: %s", config.AllowlistPath, stdout.String())
+	}
+}
+
+func TestRunValidate_JSONFormat(t *testing.T) {
+	valid := writeCLIAllowlist(t, cliValidAllowlist)
+	invalid := writeCLIAllowlist(t, cliInvalidAllowlist)
+
+	var stdout, stderr bytes.Buffer
+	code := runValidate([]string{"--format", "json", valid, invalid}, testConfig(), &stdout, &stderr)
+	if code != exitInvalid {
+		t.Errorf("expected exit code %d, got %d", exitInvalid, code)
+	}
+
+	var output struct {
+		Files []fileResult `json:"files"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		t.Fatalf("failed to decode output: %v\n%s", err, stdout.String())
+	}
+	if len(output.Files) != 2 {
+		t.Fatalf("expected 2 file results, got %d", len(output.Files))
+	}
+	if !output.Files[0].Valid || len(output.Files[0].Errors) != 0 {
+		t.Errorf("expected first file to be valid, got %+v", output.Files[0])
+	}
+	if output.Files[1].Valid || len(output.Files[1].Errors) < 3 {
+		t.Errorf("expected second file to report at least 3 errors, got %+v", output.Files[1])
+	}
+}
+
+func TestRunValidate_GitHubFormat(t *testing.T) {
+	warning := writeCLIAllowlist(t, cliWarningAllowlist)
+	invalid := writeCLIAllowlist(t, cliInvalidAllowlist)
+
+	var stdout, stderr bytes.Buffer
+	runValidate([]string{"--format", "github", warning, invalid}, testConfig(), &stdout, &stderr)
+	output := stdout.String()
+
+	if !strings.Contains(output, "::warning file="+escapeGitHubProperty(warning)+",title=Allowlist warning::server[0]: 'publishedAt' is missing") {
+		t.Errorf("expected warning annotation, got:\n%s", output)
+	}
+	if !strings.Contains(output, "::error file="+escapeGitHubProperty(invalid)+",title=Invalid allowlist::") {
+		t.Errorf("expected error annotation, got:\n%s", output)
+	}
+
+	stdout.Reset()
+	runValidate([]string{"--format", "github", "--strict", warning}, testConfig(), &stdout, &stderr)
+	if strings.Contains(stdout.String(), "::warning") || !strings.Contains(stdout.String(), "::error") {
+		t.Errorf("expected warnings to be reported as errors in strict mode, got:\n%s", stdout.String())
+	}
+}
+
+func TestEscapeGitHub(t *testing.T) {
+	if got := escapeGitHubData("100%\nnext\r"); got != "100%25%0Anext%0D" {
+		t.Errorf("escapeGitHubData: got %q", got)
+	}
+	if got := escapeGitHubProperty("a:b,c"); got != "a%3Ab%2Cc" {
+		t.Errorf("escapeGitHubProperty: got %q", got)
+	}
+}
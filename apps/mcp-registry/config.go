@@ -2,9 +2,13 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds application configuration loaded from environment variables.
@@ -14,15 +18,100 @@ type Config struct {
 	LoggedEndpoints map[string]bool
 	DomainInternal  string
 	DomainExternal  string
+	AllowlistPath   string
+	// PublicKeyFile is a PEM public key the allowlist file must be signed
+	// with, or empty to serve it unsigned.
+	PublicKeyFile  string
+	ReloadInterval time.Duration
+	VariablesFile  string
+	Variables      map[string]string
+	CacheControl   string
+	UpstreamURL    string
+	UpstreamSync   time.Duration
+	AdminToken     string
+	OIDCIssuer     string
+	OIDCAudience   string
+	OIDCJWKSURI    string
+	AdminGroups    []string
+	DatabaseURL    string
+	// HealthProbeInterval is how often remotes are probed, or 0, the
+	// default, to disable probing.
+	HealthProbeInterval time.Duration
+	// Environment and Audience select the servers this registry serves. Both
+	// are empty when running outside NAIS, which serves every server.
+	Environment string
+	Audience    string
+	// CORSAllowedOrigins lists the origins browsers may read responses
+	// from, or "*" for any origin.
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	// CORSMaxAge is how long browsers may cache a preflight response.
+	CORSMaxAge time.Duration
+	// ShutdownDelay is how long the server keeps serving with /ready
+	// failing after SIGTERM, so the pod is removed from the load balancer
+	// first, and ShutdownTimeout how long in-flight requests then get to
+	// finish. Together they must fit in the pod's termination grace period.
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+	// ReviewMaxAgeMonths is how old the security review of a high-risk
+	// server may be before it has expired.
+	ReviewMaxAgeMonths int
 }
 
-func loadConfig() *Config {
+// loadConfig reads the configuration from the environment. It returns an
+// error for settings that would make the registry serve the wrong servers.
+func loadConfig() (*Config, error) {
 	config := &Config{
 		Port:            getEnv("PORT", "8080"),
 		DomainInternal:  getEnv("DOMAIN_INTERNAL", "intern.dev.nav.no"),
 		DomainExternal:  getEnv("DOMAIN_EXTERNAL", "ekstern.dev.nav.no"),
+		AllowlistPath:   getEnv("ALLOWLIST_PATH", "allowlist.json"),
+		PublicKeyFile:   getEnv("ALLOWLIST_PUBLIC_KEY", ""),
+		ReloadInterval:  getEnvDuration("ALLOWLIST_RELOAD_INTERVAL", 10*time.Second),
+		VariablesFile:   getEnv("REGISTRY_VARS_FILE", ""),
+		CacheControl:    getEnv("CACHE_CONTROL", "public, max-age=60"),
+		UpstreamURL:     getEnv("UPSTREAM_REGISTRY_URL", ""),
+		UpstreamSync:    getEnvDuration("UPSTREAM_SYNC_INTERVAL", time.Hour),
+		AdminToken:      getEnv("ADMIN_TOKEN", ""),
+		OIDCIssuer:      getEnv("AZURE_OPENID_CONFIG_ISSUER", ""),
+		OIDCAudience:    getEnv("AZURE_APP_CLIENT_ID", ""),
+		OIDCJWKSURI:     getEnv("AZURE_OPENID_CONFIG_JWKS_URI", ""),
+		DatabaseURL:     getEnv("DATABASE_URL", ""),
 		LoggedEndpoints: make(map[string]bool),
+		AdminGroups:     getEnvList("ADMIN_GROUPS", ""),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS", "*"),
+		CORSAllowedMethods: getEnvList("CORS_ALLOWED_METHODS", "GET, OPTIONS"),
+		CORSAllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS", "Authorization, Content-Type"),
+		CORSMaxAge:         getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+
+		ShutdownDelay:   getEnvDuration("SHUTDOWN_DELAY", 5*time.Second),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+
+		ReviewMaxAgeMonths: getEnvInt("REVIEW_MAX_AGE_MONTHS", 12),
+	}
+
+	// Probing is opt-in, since it sends requests to every remote, which
+	// the outbound access policy must allow.
+	if os.Getenv("HEALTH_PROBE_INTERVAL") != "0" {
+		config.HealthProbeInterval = getEnvDuration("HEALTH_PROBE_INTERVAL", 0)
+	}
+
+	config.Environment = getEnv("REGISTRY_ENVIRONMENT", environmentFromCluster(os.Getenv("NAIS_CLUSTER_NAME")))
+	if config.Environment != "" && !slices.Contains(knownEnvironments, config.Environment) {
+		return nil, fmt.Errorf("unknown REGISTRY_ENVIRONMENT '%s', must be one of %s", config.Environment, strings.Join(knownEnvironments, ", "))
+	}
+	config.Audience = getEnv("REGISTRY_AUDIENCE", "")
+	if config.Audience != "" && !slices.Contains(knownAudiences, config.Audience) {
+		return nil, fmt.Errorf("unknown REGISTRY_AUDIENCE '%s', must be one of %s", config.Audience, strings.Join(knownAudiences, ", "))
+	}
+
+	variables, err := loadVariables(config.VariablesFile, os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGISTRY_VARS_FILE '%s': %v", config.VariablesFile, err)
 	}
+	config.Variables = variables
 
 	logLevelStr := getEnv("LOG_LEVEL", "INFO")
 	switch strings.ToUpper(logLevelStr) {
@@ -49,7 +138,7 @@ func loadConfig() *Config {
 		}
 	}
 
-	return config
+	return config, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -59,6 +148,45 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList returns the comma-separated values of an environment variable,
+// with blank values dropped.
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for value := range strings.SplitSeq(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// getEnvInt returns the positive integer value of an environment variable.
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		slog.Warn("Invalid number, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Warn("Invalid duration, using default", "key", key, "value", value, "default", defaultValue.String())
+		return defaultValue
+	}
+	return duration
+}
+
 func getEndpointsList(endpoints map[string]bool) []string {
 	list := make([]string, 0, len(endpoints))
 	for endpoint := range endpoints {
@@ -2,15 +2,29 @@ package main
 
 import (
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
+func loadTestConfig(t *testing.T) *Config {
+	t.Helper()
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return config
+}
+
 func TestLoadConfig_Defaults(t *testing.T) {
 	t.Setenv("PORT", "")
 	t.Setenv("LOG_LEVEL", "")
 	t.Setenv("LOGGED_ENDPOINTS", "")
 
-	config := loadConfig()
+	config := loadTestConfig(t)
 
 	if config.Port != "8080" {
 		t.Errorf("expected default port 8080, got %s", config.Port)
@@ -40,7 +54,7 @@ func TestLoadConfig_Defaults(t *testing.T) {
 func TestLoadConfig_CustomPort(t *testing.T) {
 	t.Setenv("PORT", "3000")
 
-	config := loadConfig()
+	config := loadTestConfig(t)
 
 	if config.Port != "3000" {
 		t.Errorf("expected port 3000, got %s", config.Port)
@@ -70,7 +84,7 @@ func TestLoadConfig_LogLevels(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Setenv("LOG_LEVEL", tt.envValue)
 
-			config := loadConfig()
+			config := loadTestConfig(t)
 
 			if config.LogLevel != tt.expectedLevel {
 				t.Errorf("expected log level %s, got %s", tt.expectedLevel.String(), config.LogLevel.String())
@@ -147,7 +161,7 @@ func TestLoadConfig_CustomEndpoints(t *testing.T) {
 				t.Setenv("LOGGED_ENDPOINTS", "")
 			}
 
-			config := loadConfig()
+			config := loadTestConfig(t)
 
 			if len(config.LoggedEndpoints) != len(tt.expectedEndpoints) {
 				t.Errorf("expected %d logged endpoints, got %d", len(tt.expectedEndpoints), len(config.LoggedEndpoints))
@@ -266,3 +280,189 @@ func TestGetEndpointsList(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfig_Allowlist(t *testing.T) {
+	t.Setenv("ALLOWLIST_PATH", "")
+	t.Setenv("ALLOWLIST_RELOAD_INTERVAL", "")
+
+	config := loadTestConfig(t)
+
+	if config.AllowlistPath != "allowlist.json" {
+		t.Errorf("expected default allowlist path allowlist.json, got %s", config.AllowlistPath)
+	}
+
+	if config.ReloadInterval != 10*time.Second {
+		t.Errorf("expected default reload interval 10s, got %s", config.ReloadInterval)
+	}
+
+	t.Setenv("ALLOWLIST_PATH", "/etc/registry/allowlist.json")
+	t.Setenv("ALLOWLIST_RELOAD_INTERVAL", "30s")
+
+	config = loadTestConfig(t)
+
+	if config.AllowlistPath != "/etc/registry/allowlist.json" {
+		t.Errorf("expected custom allowlist path, got %s", config.AllowlistPath)
+	}
+
+	if config.ReloadInterval != 30*time.Second {
+		t.Errorf("expected reload interval 30s, got %s", config.ReloadInterval)
+	}
+}
+
+func TestLoadConfig_HealthProbeInterval(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30s", 30 * time.Second},
+		{"0", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("HEALTH_PROBE_INTERVAL", tt.value)
+			if interval := loadTestConfig(t).HealthProbeInterval; interval != tt.expected {
+				t.Errorf("expected health probe interval %s, got %s", tt.expected, interval)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Scope(t *testing.T) {
+	tests := []struct {
+		name                string
+		cluster             string
+		environment         string
+		audience            string
+		expectedEnvironment string
+		expectedAudience    string
+	}{
+		{"outside NAIS", "", "", "", "", ""},
+		{"from NAIS cluster", "prod-gcp", "", "", EnvironmentProd, ""},
+		{"explicit environment wins", "prod-gcp", EnvironmentDev, "", EnvironmentDev, ""},
+		{"audience", "dev-gcp", "", AudienceExternal, EnvironmentDev, AudienceExternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NAIS_CLUSTER_NAME", tt.cluster)
+			t.Setenv("REGISTRY_ENVIRONMENT", tt.environment)
+			t.Setenv("REGISTRY_AUDIENCE", tt.audience)
+
+			config := loadTestConfig(t)
+			if config.Environment != tt.expectedEnvironment || config.Audience != tt.expectedAudience {
+				t.Errorf("expected environment %q and audience %q, got %q and %q", tt.expectedEnvironment, tt.expectedAudience, config.Environment, config.Audience)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_UnknownScope(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		audience    string
+		expected    string
+	}{
+		{"unknown environment", "staging", "", "REGISTRY_ENVIRONMENT"},
+		{"unknown audience", "", "public", "REGISTRY_AUDIENCE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REGISTRY_ENVIRONMENT", tt.environment)
+			t.Setenv("REGISTRY_AUDIENCE", tt.audience)
+
+			if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected %s to be rejected, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{"valid duration", "5m", 5 * time.Minute},
+		{"empty uses default", "", time.Second},
+		{"invalid uses default", "soon", time.Second},
+		{"negative uses default", "-5s", time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_DURATION", tt.envValue)
+
+			result := getEnvDuration("TEST_DURATION", time.Second)
+
+			if result != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Variables(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "variables.json")
+	if err := os.WriteFile(file, []byte(`{"cluster": "dev-gcp", "environment": "dev"}`), 0o644); err != nil {
+		t.Fatalf("failed to write variables file: %v", err)
+	}
+	t.Setenv("REGISTRY_VARS_FILE", file)
+	t.Setenv("REGISTRY_VAR_ENVIRONMENT", "prod")
+
+	config := loadTestConfig(t)
+
+	if config.Variables["cluster"] != "dev-gcp" {
+		t.Errorf("expected cluster from variables file, got %q", config.Variables["cluster"])
+	}
+	if config.Variables["environment"] != "prod" {
+		t.Errorf("expected environment from REGISTRY_VAR_ENVIRONMENT to win, got %q", config.Variables["environment"])
+	}
+}
+
+func TestLoadConfig_InvalidVariables(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "variables.json")
+	if err := os.WriteFile(file, []byte(`{"cluster": `), 0o644); err != nil {
+		t.Fatalf("failed to write variables file: %v", err)
+	}
+	t.Setenv("REGISTRY_VARS_FILE", file)
+
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("expected invalid variables file to be rejected, got %v", err)
+	}
+
+	t.Setenv("REGISTRY_VARS_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected missing variables file to be rejected")
+	}
+}
+
+func TestLoadConfig_CORS(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("CORS_ALLOWED_METHODS", "")
+	t.Setenv("CORS_ALLOWED_HEADERS", "")
+	t.Setenv("CORS_MAX_AGE", "")
+
+	config := loadTestConfig(t)
+	if !slices.Equal(config.CORSAllowedOrigins, []string{"*"}) || !slices.Equal(config.CORSAllowedMethods, []string{"GET", "OPTIONS"}) ||
+		!slices.Equal(config.CORSAllowedHeaders, []string{"Authorization", "Content-Type"}) || config.CORSMaxAge != 10*time.Minute {
+		t.Errorf("unexpected default CORS policy: %v %v %v %s", config.CORSAllowedOrigins, config.CORSAllowedMethods, config.CORSAllowedHeaders, config.CORSMaxAge)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://portal.nav.no, ,https://portal.intern.nav.no")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET")
+	t.Setenv("CORS_MAX_AGE", "1h")
+
+	config = loadTestConfig(t)
+	if !slices.Equal(config.CORSAllowedOrigins, []string{"https://portal.nav.no", "https://portal.intern.nav.no"}) {
+		t.Errorf("expected listed origins, got %v", config.CORSAllowedOrigins)
+	}
+	if !slices.Equal(config.CORSAllowedMethods, []string{"GET"}) || config.CORSMaxAge != time.Hour {
+		t.Errorf("expected GET with max age 1h, got %v and %s", config.CORSAllowedMethods, config.CORSMaxAge)
+	}
+}
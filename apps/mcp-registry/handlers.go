@@ -1,252 +1,295 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/url"
-	"os"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/navikt/copilot/libs/problem"
 )
 
 func healthHandler(w http.ResponseWriter, _ *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
 }
 
-func readyHandler(w http.ResponseWriter, _ *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+// makeReadyHandler returns the readiness probe, which fails until a valid
+// allowlist is loaded and again once the server is shutting down. Unlike
+// /health, a failure only takes the pod out of rotation.
+func makeReadyHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := registry.Ready(); err != nil {
+			problem.Write(w, r, problemNotReady.New(err.Error()))
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	}
+}
+
+// loadedSnapshot returns the snapshot the registry serves. Before the first
+// allowlist is loaded there is none, and it writes the 503 problem of /ready
+// and returns nil.
+func loadedSnapshot(w http.ResponseWriter, r *http.Request, registry *Registry) *registrySnapshot {
+	snapshot := registry.Snapshot()
+	if snapshot == nil {
+		slog.Warn("Allowlist not loaded yet", "path", r.URL.Path)
+		problem.Write(w, r, problemNotReady.New(errNotLoaded.Error()))
+	}
+	return snapshot
 }
 
-func metricsHandler(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	if _, err := fmt.Fprintf(w, "# HELP mcp_registry_requests_total Total number of requests\n"); err != nil {
-		slog.Error("Failed to write metrics help", "error", err)
+func makeServersListHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serversListHandler(w, r, registry)
+	}
+}
+
+func serversListHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		slog.Warn("Invalid query parameters", "query", r.URL.RawQuery, "error", err)
+		problem.Write(w, r, problemInvalidQuery.New(err.Error()))
 		return
 	}
-	if _, err := fmt.Fprintf(w, "# TYPE mcp_registry_requests_total counter\n"); err != nil {
-		slog.Error("Failed to write metrics type", "error", err)
+
+	snapshot := loadedSnapshot(w, r, registry)
+	if snapshot == nil {
 		return
 	}
-	if _, err := fmt.Fprintf(w, "mcp_registry_requests_total 0\n"); err != nil {
-		slog.Error("Failed to write metrics value", "error", err)
+
+	if checkNotModified(w, r, snapshot, registry.config.CacheControl) {
+		return
 	}
-}
 
-func rootHandler(w http.ResponseWriter, _ *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"service":     "Nav MCP Registry",
-		"version":     "1.0.0",
-		"description": "Nav internal MCP server registry providing approved servers for GitHub Copilot",
-		"endpoints": map[string]string{
-			"servers":        "/v0.1/servers",
-			"server_version": "/v0.1/servers/{serverName}/versions/{version}",
-			"health":         "/health",
-			"ready":          "/ready",
-			"metrics":        "/metrics",
+	servers, nextCursor := query.apply(snapshot.Servers())
+
+	response := ServerListResponse{
+		Servers: servers,
+		Metadata: Metadata{
+			NextCursor: nextCursor,
+			Count:      len(servers),
 		},
-	})
-}
+	}
 
-func optionsHandler(w http.ResponseWriter, _ *http.Request) {
-	setCORSHeaders(w)
-	w.WriteHeader(http.StatusNoContent)
+	slog.Debug("Returning servers list", "server_count", len(servers))
+	respondJSON(w, http.StatusOK, response)
 }
 
-func substituteVariables(data []byte, config *Config) []byte {
-	result := string(data)
-	result = strings.ReplaceAll(result, "{{domain_internal}}", config.DomainInternal)
-	result = strings.ReplaceAll(result, "{{domain_external}}", config.DomainExternal)
-	return []byte(result)
+func makeServerVersionsHandler(registry *Registry, metrics *Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serverVersionsListHandler(w, r, registry, metrics)
+	}
 }
 
-func makeServersListHandler(config *Config) http.HandlerFunc {
+func makeServerVersionHandler(registry *Registry, metrics *Metrics) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		serversListHandler(w, r, config)
+		serverVersionHandler(w, r, registry, metrics)
 	}
 }
 
-func serversListHandler(w http.ResponseWriter, r *http.Request, config *Config) {
-	if r.Method == http.MethodOptions {
-		optionsHandler(w, r)
+func serverVersionHandler(w http.ResponseWriter, r *http.Request, registry *Registry, metrics *Metrics) {
+	serverName, version := r.PathValue("name"), r.PathValue("version")
+
+	snapshot := loadedSnapshot(w, r, registry)
+	if snapshot == nil {
 		return
 	}
-	if r.Method != http.MethodGet {
-		slog.Warn("Method not allowed", "method", r.Method, "path", r.URL.Path)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	response, ok := snapshot.Find(serverName, version)
+	if !ok {
+		metrics.ServerNotFound(serverName)
+		slog.Warn("Server not found", "name", serverName, "version", version)
+		problem.Write(w, r, serverProblem(problemServerNotFound, fmt.Sprintf("Server '%s' has no version '%s'", serverName, version), serverName, version))
 		return
 	}
 
-	data, err := os.ReadFile("allowlist.json")
-	if err != nil {
-		slog.Error("Error reading allowlist.json", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if response.Meta.Official.Status == StatusDeleted {
+		slog.Debug("Server version is deleted", "name", serverName, "version", version)
+		problem.Write(w, r, serverProblem(problemServerDeleted, fmt.Sprintf("Version '%s' of server '%s' is deleted", version, serverName), serverName, version))
 		return
 	}
 
-	data = substituteVariables(data, config)
+	if checkNotModified(w, r, snapshot, registry.config.CacheControl) {
+		return
+	}
 
-	fileInfo, err := os.Stat("allowlist.json")
+	slog.Debug("Returning server", "name", serverName, "version", version)
+	respondJSON(w, http.StatusOK, response)
+}
+
+func serverVersionsListHandler(w http.ResponseWriter, r *http.Request, registry *Registry, metrics *Metrics) {
+	serverName := r.PathValue("name")
+	includeDeleted, err := parseIncludeDeleted(r.URL.Query())
 	if err != nil {
-		slog.Error("Error getting file info", "error", err, "file", "allowlist.json")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		slog.Warn("Invalid query parameters", "query", r.URL.RawQuery, "error", err)
+		problem.Write(w, r, problemInvalidQuery.New(err.Error()))
 		return
 	}
 
-	var staticData StaticRegistryData
-	if err := json.Unmarshal(data, &staticData); err != nil {
-		slog.Error("Unexpected error parsing allowlist.json", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	snapshot := loadedSnapshot(w, r, registry)
+	if snapshot == nil {
+		return
+	}
+	versions := snapshot.Versions(serverName)
+	if len(versions) == 0 {
+		metrics.ServerNotFound(serverName)
+		slog.Warn("Server not found", "name", serverName)
+		problem.Write(w, r, serverProblem(problemServerNotFound, fmt.Sprintf("Server '%s' is not in the registry", serverName), serverName, ""))
 		return
 	}
 
-	updatedAt := fileInfo.ModTime().UTC()
-	servers := make([]ServerResponse, 0, len(staticData.Servers))
-	for _, s := range staticData.Servers {
-		publishedAt := updatedAt
-		if s.PublishedAt != "" {
-			if parsed, err := time.Parse(time.RFC3339, s.PublishedAt); err == nil {
-				publishedAt = parsed
-			}
-		}
-		status := s.Status
-		if status == "" {
-			status = StatusActive
-		}
-		servers = append(servers, ServerResponse{
-			Server: ServerJSON{
-				Schema:      CurrentSchemaURL,
-				Name:        s.Name,
-				Description: s.Description,
-				Version:     s.Version,
-				Remotes:     s.Remotes,
-			},
-			Meta: ResponseMeta{
-				Official: &RegistryExtensions{
-					Status:      status,
-					PublishedAt: publishedAt,
-					UpdatedAt:   updatedAt,
-					IsLatest:    true,
-				},
-			},
+	if !includeDeleted {
+		versions = slices.DeleteFunc(slices.Clone(versions), func(s ServerResponse) bool {
+			return s.Meta.Official.Status == StatusDeleted
 		})
+		if len(versions) == 0 {
+			slog.Debug("Every server version is deleted", "name", serverName)
+			problem.Write(w, r, serverProblem(problemServerDeleted, fmt.Sprintf("Every version of server '%s' is deleted", serverName), serverName, ""))
+			return
+		}
+	}
+
+	if checkNotModified(w, r, snapshot, registry.config.CacheControl) {
+		return
 	}
 
 	response := ServerListResponse{
-		Servers: servers,
+		Servers: versions,
 		Metadata: Metadata{
-			Count: len(servers),
+			Count: len(versions),
 		},
 	}
 
-	slog.Debug("Returning servers list", "server_count", len(servers))
-	setCORSHeaders(w)
+	slog.Debug("Returning server versions", "name", serverName, "version_count", len(versions))
 	respondJSON(w, http.StatusOK, response)
 }
 
-func makeServerVersionHandler(config *Config) http.HandlerFunc {
+func makeServerConfigHandler(registry *Registry, metrics *Metrics) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		serverVersionHandler(w, r, config)
+		serverConfigHandler(w, r, registry, metrics)
 	}
 }
 
-func serverVersionHandler(w http.ResponseWriter, r *http.Request, config *Config) {
-	if r.Method == http.MethodOptions {
-		optionsHandler(w, r)
+// serverConfigHandler serves the configuration of a server version for the
+// client in the client query parameter.
+func serverConfigHandler(w http.ResponseWriter, r *http.Request, registry *Registry, metrics *Metrics) {
+	serverName, version := r.PathValue("name"), r.PathValue("version")
+	client, err := parseClient(r.URL.Query())
+	if err != nil {
+		slog.Warn("Invalid query parameters", "query", r.URL.RawQuery, "error", err)
+		problem.Write(w, r, problemInvalidQuery.New(err.Error()))
 		return
 	}
-	if r.Method != http.MethodGet {
-		slog.Warn("Method not allowed", "method", r.Method, "path", r.URL.Path)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	snapshot := loadedSnapshot(w, r, registry)
+	if snapshot == nil {
+		return
+	}
+	server, ok := snapshot.Find(serverName, version)
+	if !ok {
+		metrics.ServerNotFound(serverName)
+		slog.Warn("Server not found", "name", serverName, "version", version)
+		problem.Write(w, r, serverProblem(problemServerNotFound, fmt.Sprintf("Server '%s' has no version '%s'", serverName, version), serverName, version))
 		return
 	}
 
-	path := strings.TrimPrefix(r.URL.Path, "/v0.1/servers/")
-	parts := strings.Split(path, "/versions/")
-	if len(parts) != 2 {
-		slog.Warn("Invalid path format", "path", r.URL.Path)
-		http.Error(w, "Invalid path format", http.StatusBadRequest)
+	if server.Meta.Official.Status == StatusDeleted {
+		slog.Debug("Server version is deleted", "name", serverName, "version", version)
+		problem.Write(w, r, serverProblem(problemServerDeleted, fmt.Sprintf("Version '%s' of server '%s' is deleted", version, serverName), serverName, version))
 		return
 	}
 
-	serverName, err := url.PathUnescape(parts[0])
-	if err != nil {
-		slog.Warn("Invalid server name encoding", "encoded", parts[0], "error", err)
-		http.Error(w, "Invalid server name encoding", http.StatusBadRequest)
+	response, ok := newClientConfig(&server.Server, client)
+	if !ok {
+		slog.Debug("Server has no client configuration", "name", serverName, "version", version, "client", client)
+		problem.Write(w, r, serverProblem(problemNoClientConfig, fmt.Sprintf("Server '%s' has no remote or package that %s can be configured with", serverName, client), serverName, version).With("client", client))
 		return
 	}
-	version := parts[1]
 
-	data, err := os.ReadFile("allowlist.json")
-	if err != nil {
-		slog.Error("Error reading allowlist.json", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if checkNotModified(w, r, snapshot, registry.config.CacheControl) {
 		return
 	}
 
-	data = substituteVariables(data, config)
+	slog.Debug("Returning server config", "name", serverName, "version", version, "client", client)
+	respondJSON(w, http.StatusOK, response)
+}
 
-	fileInfo, err := os.Stat("allowlist.json")
-	if err != nil {
-		slog.Error("Error getting file info", "error", err, "file", "allowlist.json")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+// makeSignatureHandler returns the signature of the allowlist file the
+// current snapshot was loaded from, with the public key that verified it.
+func makeSignatureHandler(registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot := loadedSnapshot(w, r, registry)
+		if snapshot == nil {
+			return
+		}
+		signed := snapshot.signed
+		if signed == nil {
+			problem.Write(w, r, problemNotSigned.New("The registry serves an allowlist without a signature"))
+			return
+		}
+
+		if checkNotModified(w, r, snapshot, registry.config.CacheControl) {
+			return
+		}
+
+		digest := sha256.Sum256(signed.Document)
+		respondJSON(w, http.StatusOK, SignatureResponse{
+			Algorithm: signed.Algorithm,
+			PublicKey: string(signed.PublicKey),
+			Signature: signed.Signature,
+			Digest:    "sha256:" + hex.EncodeToString(digest[:]),
+			Document:  signed.Document,
+		})
 	}
+}
 
-	var staticData StaticRegistryData
-	if err := json.Unmarshal(data, &staticData); err != nil {
-		slog.Error("Unexpected error parsing allowlist.json", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+// checkNotModified sets the cache validators of snapshot on the response and
+// reports whether the request's conditional headers match them. In that case a
+// 304 Not Modified has been written and the caller must not write a body.
+// If-None-Match takes precedence over If-Modified-Since, as in RFC 9110.
+func checkNotModified(w http.ResponseWriter, r *http.Request, snapshot *registrySnapshot, cacheControl string) bool {
+	if snapshot.etag != "" {
+		w.Header().Set("ETag", snapshot.etag)
+	}
+	w.Header().Set("Last-Modified", snapshot.modifiedAt.UTC().Format(http.TimeFormat))
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
 	}
 
-	updatedAt := fileInfo.ModTime().UTC()
-	for _, s := range staticData.Servers {
-		if s.Name == serverName && (version == "latest" || s.Version == version) {
-			publishedAt := updatedAt
-			if s.PublishedAt != "" {
-				if parsed, err := time.Parse(time.RFC3339, s.PublishedAt); err == nil {
-					publishedAt = parsed
-				}
-			}
-			status := s.Status
-			if status == "" {
-				status = StatusActive
-			}
-			response := ServerResponse{
-				Server: ServerJSON{
-					Schema:      CurrentSchemaURL,
-					Name:        s.Name,
-					Description: s.Description,
-					Version:     s.Version,
-					Remotes:     s.Remotes,
-				},
-				Meta: ResponseMeta{
-					Official: &RegistryExtensions{
-						Status:      status,
-						PublishedAt: publishedAt,
-						UpdatedAt:   updatedAt,
-						IsLatest:    true,
-					},
-				},
-			}
-			slog.Debug("Returning server", "name", serverName, "version", version)
-			setCORSHeaders(w)
-			respondJSON(w, http.StatusOK, response)
-			return
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etagMatches(inm, snapshot.etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if since, err := http.ParseTime(ims); err == nil {
+			notModified = !snapshot.modifiedAt.Truncate(time.Second).After(since)
 		}
 	}
 
-	slog.Warn("Server not found", "name", serverName, "version", version)
-	http.Error(w, "Server not found", http.StatusNotFound)
+	if !notModified {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
 }
 
-func setCORSHeaders(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison required for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
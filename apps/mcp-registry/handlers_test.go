@@ -1,19 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/navikt/copilot/libs/problem"
 )
 
 func testConfig() *Config {
 	return &Config{
-		DomainInternal: "intern.dev.nav.no",
-		DomainExternal: "ekstern.dev.nav.no",
+		DomainInternal:     "intern.dev.nav.no",
+		DomainExternal:     "ekstern.dev.nav.no",
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: []string{"GET", "OPTIONS"},
+		CORSAllowedHeaders: []string{"Authorization", "Content-Type"},
+		CORSMaxAge:         10 * time.Minute,
+		ReviewMaxAgeMonths: 12,
+	}
+}
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	registry := NewRegistry(newFileStore("allowlist.json", testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist.json: %v", err)
 	}
+	return registry
 }
 
 func TestHealthHandler(t *testing.T) {
@@ -44,17 +64,87 @@ func TestHealthHandler(t *testing.T) {
 	}
 }
 
+// serveTestRequest serves req through the routes of registry.
+func serveTestRequest(w http.ResponseWriter, req *http.Request, registry *Registry) {
+	newRouter(registry.config, registry, NewMetrics(), nil).ServeHTTP(w, req)
+}
+
 func TestReadyHandler(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
-	w := httptest.NewRecorder()
+	registry := NewRegistry(newFileStore("allowlist.json", testConfig()), testConfig(), nil)
+	router := newRouter(registry.config, registry, NewMetrics(), nil)
+
+	steps := []struct {
+		name       string
+		transition func(t *testing.T)
+		wantReady  int
+	}{
+		{
+			name:       "before first load",
+			transition: func(*testing.T) {},
+			wantReady:  http.StatusServiceUnavailable,
+		},
+		{
+			name: "after load",
+			transition: func(t *testing.T) {
+				if err := registry.Load(); err != nil {
+					t.Fatalf("failed to load allowlist.json: %v", err)
+				}
+			},
+			wantReady: http.StatusOK,
+		},
+		{
+			name:       "while draining",
+			transition: func(*testing.T) { registry.Drain() },
+			wantReady:  http.StatusServiceUnavailable,
+		},
+	}
 
-	readyHandler(w, req)
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.transition(t)
 
-	resp := w.Result()
-	defer func() { _ = resp.Body.Close() }()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if w.Code != step.wantReady {
+				t.Errorf("/ready: expected status %d, got %d", step.wantReady, w.Code)
+			}
+			if w.Code != http.StatusOK && w.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("/ready: expected %s, got %q", problem.ContentType, w.Header().Get("Content-Type"))
+			}
 
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected status 200, got %d", resp.StatusCode)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("/health: expected status 200, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_BeforeFirstLoad(t *testing.T) {
+	registry := NewRegistry(newFileStore("allowlist.json", testConfig()), testConfig(), nil)
+
+	paths := []string{
+		"/v0.1/servers",
+		"/v0.1/servers/io.github.navikt%2Fmcp-onboarding/versions",
+		"/v0.1/servers/io.github.navikt%2Fmcp-onboarding/versions/latest",
+		"/v0.1/servers/io.github.navikt%2Fmcp-onboarding/versions/latest/config",
+		"/v0.1/servers/io.github.navikt/mcp-onboarding/versions/latest",
+		"/v0.1/signature",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			serveTestRequest(w, httptest.NewRequest(http.MethodGet, path, nil), registry)
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("expected status 503, got %d: %s", w.Code, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("expected Content-Type %s, got %s", problem.ContentType, contentType)
+			}
+		})
 	}
 }
 
@@ -62,7 +152,7 @@ func TestServersListHandler(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers", nil)
 	w := httptest.NewRecorder()
 
-	serversListHandler(w, req, testConfig())
+	serversListHandler(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -123,7 +213,7 @@ func TestServersListHandler_MethodNotAllowed(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/v0.1/servers", nil)
 	w := httptest.NewRecorder()
 
-	serversListHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -137,7 +227,7 @@ func TestServersListHandler_Options(t *testing.T) {
 	req := httptest.NewRequest(http.MethodOptions, "/v0.1/servers", nil)
 	w := httptest.NewRecorder()
 
-	serversListHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -157,7 +247,7 @@ func TestServerVersionHandler_Latest(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+encodedName+"/versions/latest", nil)
 	w := httptest.NewRecorder()
 
-	serverVersionHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -192,7 +282,7 @@ func TestServerVersionHandler_SpecificVersion(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+encodedName+"/versions/1.0.0", nil)
 	w := httptest.NewRecorder()
 
-	serverVersionHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -223,7 +313,7 @@ func TestServerVersionHandler_NotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+encodedName+"/versions/latest", nil)
 	w := httptest.NewRecorder()
 
-	serverVersionHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -237,7 +327,7 @@ func TestServerVersionHandler_InvalidPath(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/invalid-path", nil)
 	w := httptest.NewRecorder()
 
-	serverVersionHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -251,7 +341,7 @@ func TestRootHandler(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
 
-	rootHandler(w, req)
+	rootHandler(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -283,7 +373,7 @@ func TestCORSHeaders(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers", nil)
 	w := httptest.NewRecorder()
 
-	serversListHandler(w, req, testConfig())
+	serveTestRequest(w, req, testRegistry(t))
 
 	resp := w.Result()
 	defer func() { _ = resp.Body.Close() }()
@@ -300,3 +390,645 @@ func TestCORSHeaders(t *testing.T) {
 		t.Errorf("expected Access-Control-Allow-Headers 'Authorization, Content-Type', got %s", headers)
 	}
 }
+
+func TestServerVersionsListHandler(t *testing.T) {
+	serverName := "io.github.navikt/github-mcp"
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape(serverName)+"/versions", nil)
+	w := httptest.NewRecorder()
+
+	serveTestRequest(w, req, testRegistry(t))
+
+	resp := w.Result()
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response ServerListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(response.Servers) == 0 {
+		t.Fatal("expected at least one version")
+	}
+
+	if response.Metadata.Count != len(response.Servers) {
+		t.Errorf("metadata.count (%d) does not match servers length (%d)", response.Metadata.Count, len(response.Servers))
+	}
+
+	for i, sr := range response.Servers {
+		if sr.Server.Name != serverName {
+			t.Errorf("server[%d]: expected name '%s', got '%s'", i, serverName, sr.Server.Name)
+		}
+	}
+}
+
+func TestServerVersionsListHandler_NotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape("io.github.nonexistent/server")+"/versions", nil)
+	w := httptest.NewRecorder()
+
+	serveTestRequest(w, req, testRegistry(t))
+
+	resp := w.Result()
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+const deprecationTestAllowlist = `{"servers": [
+	{"name": "io.github.test/server", "owner": "team-test", "description": "Current", "version": "2.0.0"},
+	{"name": "io.github.test/server", "owner": "team-test", "description": "Old", "version": "1.0.0", "status": "deprecated",
+		"deprecationMessage": "Version 1 is no longer maintained", "replacedBy": "io.github.test/other"},
+	{"name": "io.github.test/server", "description": "Broken", "version": "0.9.0", "status": "deleted"},
+	{"name": "io.github.test/other", "owner": "team-test", "description": "Other", "version": "1.0.0"},
+	{"name": "io.github.test/gone", "description": "Gone", "version": "1.0.0", "status": "deleted"}
+]}`
+
+func TestServerVersionHandler_Deprecation(t *testing.T) {
+	registry := loadTestRegistry(t, deprecationTestAllowlist, time.Now())
+
+	tests := []struct {
+		name               string
+		server             string
+		version            string
+		expectedStatus     int
+		expectedDeprecated string
+		expectedReplacedBy string
+	}{
+		{"active", "io.github.test/server", "2.0.0", http.StatusOK, "", ""},
+		{"deprecated", "io.github.test/server", "1.0.0", http.StatusOK, "Version 1 is no longer maintained", "io.github.test/other"},
+		{"deleted", "io.github.test/server", "0.9.0", http.StatusGone, "", ""},
+		{"latest of deleted server", "io.github.test/gone", VersionLatest, http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape(tt.server)+"/versions/"+tt.version, nil)
+			w := httptest.NewRecorder()
+
+			serveTestRequest(w, req, registry)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusNotFound {
+				return
+			}
+
+			if w.Code == http.StatusGone {
+				if contentType := w.Header().Get("Content-Type"); contentType != problem.ContentType {
+					t.Errorf("expected Content-Type %s, got %s", problem.ContentType, contentType)
+				}
+				var details map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil {
+					t.Fatalf("failed to parse response: %v", err)
+				}
+				if details["type"] != problemServerDeleted.URI {
+					t.Errorf("expected problem type %s, got %v", problemServerDeleted.URI, details["type"])
+				}
+				if details["name"] != tt.server || details["version"] != tt.version {
+					t.Errorf("expected name and version of the deleted version, got %v", details)
+				}
+				if _, ok := details["server"]; ok {
+					t.Errorf("expected no server entry in the problem, got %v", details["server"])
+				}
+				return
+			}
+
+			var response ServerResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if tt.expectedDeprecated == "" {
+				if response.Meta.Nav != nil && response.Meta.Nav.Deprecation != nil {
+					t.Errorf("expected no deprecation, got %+v", response.Meta.Nav.Deprecation)
+				}
+				return
+			}
+			if response.Meta.Nav == nil || response.Meta.Nav.Deprecation == nil {
+				t.Fatalf("expected deprecation in _meta, got %s", w.Body.String())
+			}
+			if deprecation := response.Meta.Nav.Deprecation; deprecation.Message != tt.expectedDeprecated || deprecation.ReplacedBy != tt.expectedReplacedBy {
+				t.Errorf("expected deprecation %q replaced by %q, got %+v", tt.expectedDeprecated, tt.expectedReplacedBy, deprecation)
+			}
+		})
+	}
+}
+
+func TestServerVersionHandler_Ownership(t *testing.T) {
+	registry := loadTestRegistry(t, `{"servers": [
+	{"name": "io.github.test/owned", "description": "Owned", "version": "1.0.0",
+		"owner": "team-test", "contact": "#team-test", "documentation": "https://docs.nav.no/owned",
+		"repository": {"url": "https://github.com/navikt/owned", "source": "github"}}
+]}`, time.Now())
+
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape("io.github.test/owned")+"/versions/1.0.0", nil)
+	w := httptest.NewRecorder()
+	serveTestRequest(w, req, registry)
+
+	var response ServerResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.Server.Repository == nil || response.Server.Repository.URL != "https://github.com/navikt/owned" {
+		t.Errorf("expected repository in server.json, got %+v", response.Server.Repository)
+	}
+	if response.Meta.Nav == nil || response.Meta.Nav.Ownership == nil {
+		t.Fatalf("expected ownership in _meta, got %s", w.Body.String())
+	}
+	expected := Ownership{
+		Owner:         "team-test",
+		OwnerURL:      "https://github.com/orgs/navikt/teams/team-test",
+		Contact:       "#team-test",
+		Repository:    "https://github.com/navikt/owned",
+		Documentation: "https://docs.nav.no/owned",
+	}
+	if *response.Meta.Nav.Ownership != expected {
+		t.Errorf("expected ownership %+v, got %+v", expected, *response.Meta.Nav.Ownership)
+	}
+}
+
+func TestServerVersionsListHandler_Deleted(t *testing.T) {
+	registry := loadTestRegistry(t, deprecationTestAllowlist, time.Now())
+
+	tests := []struct {
+		name           string
+		server         string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"deleted versions hidden", "io.github.test/server", "", http.StatusOK, 2},
+		{"deleted versions included", "io.github.test/server", "?include_deleted=true", http.StatusOK, 3},
+		{"every version deleted", "io.github.test/gone", "", http.StatusGone, 0},
+		{"every version deleted included", "io.github.test/gone", "?include_deleted=true", http.StatusOK, 1},
+		{"invalid include_deleted", "io.github.test/server", "?include_deleted=maybe", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape(tt.server)+"/versions"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			serveTestRequest(w, req, registry)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response ServerListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if response.Metadata.Count != tt.expectedCount {
+				t.Errorf("expected %d versions, got %d", tt.expectedCount, response.Metadata.Count)
+			}
+		})
+	}
+}
+
+func TestServerConfigHandler(t *testing.T) {
+	registry := loadTestRegistry(t, `{"servers": [
+	{"name": "io.github.test/remote", "owner": "team-test", "description": "Remote", "version": "1.0.0",
+		"remotes": [{"type": "streamable-http", "url": "https://remote.intern.nav.no/mcp"}]},
+	{"name": "io.github.test/remote", "description": "Remote", "version": "0.9.0", "status": "deleted",
+		"remotes": [{"type": "streamable-http", "url": "https://remote.intern.nav.no/mcp"}]},
+	{"name": "io.github.test/bundle", "owner": "team-test", "description": "Bundle", "version": "1.0.0",
+		"packages": [{"registryType": "mcpb", "identifier": "https://example.com/bundle.mcpb", "fileSha256": "fe333e598595000ae021bd27117db32ec69af6987f507ba7a63c90638ff633ce", "transport": {"type": "stdio"}}]}
+]}`, time.Now())
+
+	tests := []struct {
+		name           string
+		server         string
+		path           string
+		expectedStatus int
+		expectedClient string
+		expectedConfig string
+	}{
+		{"default client", "io.github.test/remote", "/versions/1.0.0/config", http.StatusOK, ClientVSCode, `"servers"`},
+		{"latest", "io.github.test/remote", "/versions/latest/config?client=vscode", http.StatusOK, ClientVSCode, `"servers"`},
+		{"copilot cli", "io.github.test/remote", "/versions/1.0.0/config?client=copilot-cli", http.StatusOK, ClientCopilotCLI, `"tools":["*"]`},
+		{"generic", "io.github.test/remote", "/versions/1.0.0/config?client=generic", http.StatusOK, ClientGeneric, `"mcpServers"`},
+		{"unknown client", "io.github.test/remote", "/versions/1.0.0/config?client=emacs", http.StatusBadRequest, "", ""},
+		{"unknown version", "io.github.test/remote", "/versions/2.0.0/config", http.StatusNotFound, "", ""},
+		{"deleted version", "io.github.test/remote", "/versions/0.9.0/config", http.StatusGone, "", ""},
+		{"nothing to configure", "io.github.test/bundle", "/versions/1.0.0/config", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0.1/servers/"+url.PathEscape(tt.server)+tt.path, nil)
+			w := httptest.NewRecorder()
+
+			serveTestRequest(w, req, registry)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				ClientConfigResponse
+				Config json.RawMessage `json:"config"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if response.Client != tt.expectedClient || response.Name != "remote" {
+				t.Errorf("expected %s config for remote, got %s for %s", tt.expectedClient, response.Client, response.Name)
+			}
+			if !strings.Contains(string(response.Config), tt.expectedConfig) {
+				t.Errorf("expected config to contain %s, got %s", tt.expectedConfig, response.Config)
+			}
+			if (tt.expectedClient == ClientVSCode) != (response.InstallURL != "") {
+				t.Errorf("expected install link only for VS Code, got %q", response.InstallURL)
+			}
+			if w.Header().Get("ETag") == "" {
+				t.Error("expected ETag header")
+			}
+		})
+	}
+}
+
+func TestServerVersionHandler_Problems(t *testing.T) {
+	registry := loadTestRegistry(t, deprecationTestAllowlist, time.Now())
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedStatus  int
+		expectedType    string
+		expectedName    string
+		expectedVersion string
+	}{
+		{"unknown version", http.MethodGet, "/v0.1/servers/io.github.test%2Fserver/versions/9.9.9", http.StatusNotFound, problemServerNotFound.URI, "io.github.test/server", "9.9.9"},
+		{"unknown server", http.MethodGet, "/v0.1/servers/io.github.test%2Fmissing/versions", http.StatusNotFound, problemServerNotFound.URI, "io.github.test/missing", ""},
+		{"every version deleted", http.MethodGet, "/v0.1/servers/io.github.test%2Fgone/versions", http.StatusGone, problemServerDeleted.URI, "io.github.test/gone", ""},
+		{"invalid path", http.MethodGet, "/v0.1/servers/io.github.test%2Fserver", http.StatusBadRequest, problemInvalidPath.URI, "", ""},
+		{"invalid query", http.MethodGet, "/v0.1/servers/io.github.test%2Fserver/versions?include_deleted=maybe", http.StatusBadRequest, problemInvalidQuery.URI, "", ""},
+		{"method not allowed", http.MethodPost, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", http.StatusMethodNotAllowed, "about:blank", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			serveTestRequest(w, req, registry)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("expected problem details, got %s", contentType)
+			}
+
+			var details struct {
+				Type     string `json:"type"`
+				Title    string `json:"title"`
+				Status   int    `json:"status"`
+				Detail   string `json:"detail"`
+				Instance string `json:"instance"`
+				Name     string `json:"name"`
+				Version  string `json:"version"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil {
+				t.Fatalf("failed to parse problem details: %v", err)
+			}
+			if details.Type != tt.expectedType || details.Status != tt.expectedStatus || details.Title == "" || details.Detail == "" {
+				t.Errorf("expected %s problem with status %d, got %+v", tt.expectedType, tt.expectedStatus, details)
+			}
+			if details.Instance != req.URL.EscapedPath() {
+				t.Errorf("expected instance %s, got %s", req.URL.EscapedPath(), details.Instance)
+			}
+			if details.Name != tt.expectedName || details.Version != tt.expectedVersion {
+				t.Errorf("expected name %q and version %q, got %q and %q", tt.expectedName, tt.expectedVersion, details.Name, details.Version)
+			}
+		})
+	}
+}
+
+func scrapeMetrics(t *testing.T, metrics *Metrics) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	metrics.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 from metrics endpoint, got %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func assertMetric(t *testing.T, exposition, line string) {
+	t.Helper()
+	if !strings.Contains(exposition, line+"\n") {
+		t.Errorf("expected metrics to contain %q", line)
+	}
+}
+
+func TestMetricsHandler_Requests(t *testing.T) {
+	metrics := NewMetrics()
+	registry := testRegistry(t)
+	config := testConfig()
+
+	router := newRouter(config, registry, metrics, nil)
+	serve := func(method, path string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+
+	for range 2 {
+		serve(http.MethodGet, "/v0.1/servers")
+	}
+	serve(http.MethodPost, "/v0.1/servers")
+	serve(http.MethodGet, "/v0.1/servers/"+url.PathEscape("io.github.nonexistent/server")+"/versions/latest")
+	serve(http.MethodGet, "/v0.1/servers/"+url.PathEscape("not a name")+"/versions/latest")
+
+	exposition := scrapeMetrics(t, metrics)
+
+	assertMetric(t, exposition, `mcp_registry_requests_total{method="GET",route="GET /v0.1/servers",status="200"} 2`)
+	assertMetric(t, exposition, `mcp_registry_requests_total{method="POST",route="unmatched",status="405"} 1`)
+	assertMetric(t, exposition, `mcp_registry_requests_total{method="GET",route="GET /v0.1/servers/{name}/versions/{version}",status="404"} 2`)
+	assertMetric(t, exposition, `mcp_registry_request_duration_seconds_count{method="GET",route="GET /v0.1/servers",status="200"} 2`)
+	assertMetric(t, exposition, `mcp_registry_server_not_found_total{name="unknown"} 2`)
+}
+
+func TestMetrics_ServerNotFound(t *testing.T) {
+	metrics := NewMetrics()
+	loadedAt := time.Now()
+	servers := []ServerResponse{{Server: ServerJSON{Name: "io.github.test/server"}, Meta: ResponseMeta{Official: &RegistryExtensions{Status: StatusActive}}}}
+
+	metrics.ObserveSnapshot(servers, loadedAt)
+	metrics.ServerNotFound("io.github.test/server")
+	metrics.ServerNotFound("io.github.test/missing")
+	metrics.ServerNotFound("not a name")
+
+	exposition := scrapeMetrics(t, metrics)
+	assertMetric(t, exposition, `mcp_registry_server_not_found_total{name="io.github.test/server"} 1`)
+	assertMetric(t, exposition, `mcp_registry_server_not_found_total{name="unknown"} 2`)
+
+	metrics.ObserveSnapshot(nil, loadedAt.Add(time.Hour))
+	metrics.ServerNotFound("io.github.test/server")
+	assertMetric(t, scrapeMetrics(t, metrics), `mcp_registry_server_not_found_total{name="io.github.test/server"} 2`)
+
+	metrics.ObserveSnapshot(nil, loadedAt.Add(knownNameRetention+time.Minute))
+	metrics.ServerNotFound("io.github.test/server")
+	exposition = scrapeMetrics(t, metrics)
+	if strings.Contains(exposition, `name="io.github.test/server"`) {
+		t.Error("expected a name that is no longer served to be forgotten")
+	}
+	assertMetric(t, exposition, `mcp_registry_server_not_found_total{name="unknown"} 3`)
+}
+
+func TestMetricsHandler_Allowlist(t *testing.T) {
+	metrics := NewMetrics()
+	path := t.TempDir() + "/allowlist.json"
+	writeAllowlist(t, path, `{"servers": [
+		{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0"},
+		{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "0.9.0", "status": "deprecated"}
+	]}`, time.Now().Add(-time.Hour))
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), metrics)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+
+	exposition := scrapeMetrics(t, metrics)
+
+	assertMetric(t, exposition, `mcp_registry_servers{status="active"} 1`)
+	assertMetric(t, exposition, `mcp_registry_servers{status="deprecated"} 1`)
+	assertMetric(t, exposition, `mcp_registry_servers{status="deleted"} 0`)
+	assertMetric(t, exposition, `mcp_registry_allowlist_reload_failures_total 0`)
+	if !strings.Contains(exposition, "mcp_registry_allowlist_load_timestamp_seconds ") ||
+		strings.Contains(exposition, "mcp_registry_allowlist_load_timestamp_seconds 0\n") {
+		t.Error("expected allowlist load timestamp to be set")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, 10*time.Millisecond)
+
+	writeAllowlist(t, path, `{"servers": []}`, time.Now())
+
+	if !waitFor(t, func() bool {
+		return strings.Contains(scrapeMetrics(t, metrics), "mcp_registry_allowlist_reload_failures_total 1\n")
+	}) {
+		t.Error("expected reload failure to be counted")
+	}
+}
+
+func TestServerHandlers_PackagesRoundTrip(t *testing.T) {
+	registry := loadTestRegistry(t, `{"servers": [{
+		"name": "io.github.test/gradle-mcp",
+		"description": "Gradle helper",
+		"owner": "team-test",
+		"version": "1.0.0",
+		"packages": [{
+			"registryType": "oci",
+			"identifier": "ghcr.io/navikt/gradle-mcp:1.0.0",
+			"runtimeHint": "docker",
+			"transport": {"type": "stdio"},
+			"runtimeArguments": [{"type": "named", "name": "--rm"}],
+			"packageArguments": [{"type": "positional", "valueHint": "project_dir", "format": "filepath"}],
+			"environmentVariables": [{"name": "GRADLE_USER_HOME", "owner": "team-test", "description": "Gradle home", "isRequired": true}]
+		}]
+	}]}`, time.Now())
+
+	requests := map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v0.1/servers": func(w http.ResponseWriter, r *http.Request) {
+			serversListHandler(w, r, registry)
+		},
+		"/v0.1/servers/" + url.PathEscape("io.github.test/gradle-mcp") + "/versions/1.0.0": func(w http.ResponseWriter, r *http.Request) {
+			serveTestRequest(w, r, registry)
+		},
+	}
+
+	for path, handler := range requests {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var server ServerResponse
+			if strings.HasSuffix(path, "/servers") {
+				var list ServerListResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+					t.Fatalf("failed to parse response: %v", err)
+				}
+				server = list.Servers[0]
+			} else if err := json.Unmarshal(w.Body.Bytes(), &server); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			if len(server.Server.Packages) != 1 {
+				t.Fatalf("expected 1 package, got %d", len(server.Server.Packages))
+			}
+			pkg := server.Server.Packages[0]
+			if pkg.RegistryType != RegistryTypeOCI || pkg.Identifier != "ghcr.io/navikt/gradle-mcp:1.0.0" {
+				t.Errorf("unexpected package %+v", pkg)
+			}
+			if pkg.Transport.Type != TransportTypeStdio {
+				t.Errorf("expected stdio transport, got %s", pkg.Transport.Type)
+			}
+			if len(pkg.RuntimeArguments) != 1 || pkg.RuntimeArguments[0].Name != "--rm" {
+				t.Errorf("unexpected runtime arguments %+v", pkg.RuntimeArguments)
+			}
+			if len(pkg.PackageArguments) != 1 || pkg.PackageArguments[0].Format != InputFormatFilepath {
+				t.Errorf("unexpected package arguments %+v", pkg.PackageArguments)
+			}
+			if len(pkg.EnvironmentVariables) != 1 || !pkg.EnvironmentVariables[0].IsRequired {
+				t.Errorf("unexpected environment variables %+v", pkg.EnvironmentVariables)
+			}
+		})
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	writeAllowlist(t, path, registryTestAllowlist, modTime)
+
+	config := testConfig()
+	config.CacheControl = "public, max-age=60"
+	registry := NewRegistry(newFileStore(path, config), config, nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+
+	serverPath := "/v0.1/servers/" + url.PathEscape("io.github.test/server")
+	endpoints := []struct {
+		name    string
+		path    string
+		handler func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"list", "/v0.1/servers", func(w http.ResponseWriter, r *http.Request) { serversListHandler(w, r, registry) }},
+		{"versions", serverPath + "/versions", func(w http.ResponseWriter, r *http.Request) { serveTestRequest(w, r, registry) }},
+		{"version", serverPath + "/versions/latest", func(w http.ResponseWriter, r *http.Request) { serveTestRequest(w, r, registry) }},
+	}
+
+	get := func(handler func(w http.ResponseWriter, r *http.Request), path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	for _, endpoint := range endpoints {
+		t.Run(endpoint.name, func(t *testing.T) {
+			writeAllowlist(t, path, registryTestAllowlist, modTime)
+			if err := registry.Load(); err != nil {
+				t.Fatalf("failed to load allowlist: %v", err)
+			}
+
+			first := get(endpoint.handler, endpoint.path, nil)
+			if first.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", first.Code)
+			}
+			etag := first.Header().Get("ETag")
+			if etag == "" || !strings.HasPrefix(etag, `"`) {
+				t.Errorf("expected a quoted ETag, got %q", etag)
+			}
+			if lastModified := first.Header().Get("Last-Modified"); lastModified != modTime.Format(http.TimeFormat) {
+				t.Errorf("expected Last-Modified %q, got %q", modTime.Format(http.TimeFormat), lastModified)
+			}
+			if cacheControl := first.Header().Get("Cache-Control"); cacheControl != "public, max-age=60" {
+				t.Errorf("expected configured Cache-Control, got %q", cacheControl)
+			}
+
+			tests := []struct {
+				name     string
+				headers  map[string]string
+				expected int
+			}{
+				{"matching etag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+				{"weak matching etag in list", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+				{"wildcard", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+				{"other etag", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+				{"not modified since", map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}, http.StatusNotModified},
+				{"modified since", map[string]string{"If-Modified-Since": modTime.Add(-time.Minute).Format(http.TimeFormat)}, http.StatusOK},
+				{"etag takes precedence", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modTime.Format(http.TimeFormat)}, http.StatusOK},
+				{"invalid date ignored", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+			}
+
+			for _, tt := range tests {
+				w := get(endpoint.handler, endpoint.path, tt.headers)
+				if w.Code != tt.expected {
+					t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, w.Code)
+				}
+				if w.Code == http.StatusNotModified {
+					if w.Body.Len() != 0 {
+						t.Errorf("%s: expected empty body for 304, got %q", tt.name, w.Body.String())
+					}
+					if w.Header().Get("ETag") != etag {
+						t.Errorf("%s: expected 304 to repeat ETag %q, got %q", tt.name, etag, w.Header().Get("ETag"))
+					}
+				}
+			}
+
+			changed := strings.Replace(registryTestAllowlist, "Test Description", "Changed Description", 1)
+			writeAllowlist(t, path, changed, modTime.Add(time.Hour))
+			if err := registry.Load(); err != nil {
+				t.Fatalf("failed to reload allowlist: %v", err)
+			}
+
+			w := get(endpoint.handler, endpoint.path, map[string]string{"If-None-Match": etag})
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200 after allowlist change, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "Changed Description") {
+				t.Error("expected response to contain the changed allowlist")
+			}
+			newETag := w.Header().Get("ETag")
+			if newETag == etag {
+				t.Error("expected ETag to change with the allowlist")
+			}
+
+			w = get(endpoint.handler, endpoint.path, map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)})
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200 for If-Modified-Since before the change, got %d", w.Code)
+			}
+
+			w = get(endpoint.handler, endpoint.path, map[string]string{"If-None-Match": newETag})
+			if w.Code != http.StatusNotModified {
+				t.Errorf("expected status 304 for the new ETag, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestConditionalGet_InvalidQuery(t *testing.T) {
+	registry := testRegistry(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers?limit=1", nil)
+	w := httptest.NewRecorder()
+	serversListHandler(w, req, registry)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == "" {
+		t.Fatalf("expected 200 with ETag, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0.1/servers?limit=abc", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	serversListHandler(w, req, registry)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid query to be rejected before cache validation, got %d", w.Code)
+	}
+}
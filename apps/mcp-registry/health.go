@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	probeTimeout     = 10 * time.Second
+	probeMaxBody     = 1 << 20
+	probeConcurrency = 8
+	// probeProtocolVersion is offered in initialize. Servers answer with the
+	// version they actually speak, which is what gets recorded.
+	probeProtocolVersion = "2025-06-18"
+	probeRequestID       = 1
+	probeClientName      = "mcp-registry-prober"
+)
+
+// resourceMetadataRegex finds the protected resource metadata URL in a
+// WWW-Authenticate challenge, as defined by RFC 9728.
+var resourceMetadataRegex = regexp.MustCompile(`(?i)\bresource_metadata\s*=\s*"?([^",\s]+)"?`)
+
+// authChallenge is returned by a probe when the remote answers 401 with a
+// WWW-Authenticate challenge, which means it is up but needs a token.
+type authChallenge struct {
+	header string
+}
+
+func (c *authChallenge) Error() string {
+	return "authentication required: " + c.header
+}
+
+// Prober checks that remote MCP servers answer the initialize handshake.
+type Prober struct {
+	client *http.Client
+}
+
+func NewProber(client *http.Client) *Prober {
+	if client == nil {
+		client = &http.Client{Timeout: probeTimeout, CheckRedirect: sameOriginRedirect}
+	}
+	return &Prober{client: client}
+}
+
+// sameOrigin reports whether target has the scheme and host of remote. The
+// prober only follows URLs a remote hands it, such as its SSE endpoint or
+// its resource metadata, to the remote itself. Remotes may come from the
+// public upstream registry, so anything else would let them send the
+// registry's requests anywhere inside the cluster.
+func sameOrigin(remote, target *url.URL) bool {
+	return strings.EqualFold(remote.Scheme, target.Scheme) && strings.EqualFold(remote.Host, target.Host)
+}
+
+// sameOriginRedirect refuses to follow redirects away from the remote, for
+// the same reason.
+func sameOriginRedirect(req *http.Request, via []*http.Request) error {
+	if !sameOrigin(via[0].URL, req.URL) {
+		return fmt.Errorf("redirect to %s://%s leaves the remote's origin", req.URL.Scheme, req.URL.Host)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// ProbeAll probes each remote once, a few at a time, and returns the results
+// by URL.
+func (p *Prober) ProbeAll(ctx context.Context, remotes []Transport) map[string]RemoteHealth {
+	results := make(map[string]RemoteHealth, len(remotes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, probeConcurrency)
+
+	for _, remote := range remotes {
+		wg.Go(func() {
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			health := p.Probe(ctx, remote)
+			mu.Lock()
+			results[remote.URL] = health
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Probe sends an MCP initialize request to a streamable-http or sse remote.
+// A remote that answers 401 with a WWW-Authenticate challenge counts as up,
+// and is checked for OAuth protected resource metadata.
+func (p *Prober) Probe(ctx context.Context, remote Transport) RemoteHealth {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var protocolVersion string
+	var err error
+	switch remote.Type {
+	case TransportTypeStreamableHTTP:
+		protocolVersion, err = p.initialize(ctx, remote.URL)
+	case TransportTypeSSE:
+		protocolVersion, err = p.initializeSSE(ctx, remote.URL)
+	default:
+		err = fmt.Errorf("cannot probe transport type '%s'", remote.Type)
+	}
+
+	health := RemoteHealth{
+		URL:       remote.URL,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start.UTC().Truncate(time.Second),
+	}
+
+	var challenge *authChallenge
+	switch {
+	case err == nil:
+		health.Status = HealthReachable
+		health.ProtocolVersion = protocolVersion
+	case errors.As(err, &challenge):
+		health.Status = HealthAuthRequired
+		health.OAuthMetadata = p.hasOAuthMetadata(ctx, remote.URL, challenge.header)
+	default:
+		health.Status = HealthUnreachable
+		health.Error = err.Error()
+	}
+
+	return health
+}
+
+// initialize performs the streamable-http handshake. The response may be
+// plain JSON or an event stream carrying the JSON-RPC response.
+func (p *Prober) initialize(ctx context.Context, endpoint string) (string, error) {
+	resp, err := p.postInitialize(ctx, endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkProbeResponse(resp, http.StatusOK); err != nil {
+		return "", err
+	}
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		defer p.closeSession(ctx, endpoint, session)
+	}
+
+	body := io.LimitReader(resp.Body, probeMaxBody)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		message, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("cannot read initialize response: %v", err)
+		}
+		return parseInitializeResult(message)
+	case "text/event-stream":
+		return readInitializeResult(newEventReader(body))
+	default:
+		return "", fmt.Errorf("unexpected initialize response type '%s'", mediaType)
+	}
+}
+
+// initializeSSE performs the legacy HTTP+SSE handshake: the stream announces
+// an endpoint, the initialize request is posted there, and the response
+// arrives on the stream.
+func (p *Prober) initializeSSE(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid probe request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkProbeResponse(resp, http.StatusOK); err != nil {
+		return "", err
+	}
+
+	events := newEventReader(io.LimitReader(resp.Body, probeMaxBody))
+	event, data, err := events.next()
+	if err != nil {
+		return "", err
+	}
+	if event != "endpoint" {
+		return "", fmt.Errorf("expected an endpoint event, got '%s'", event)
+	}
+	endpoint, err := resp.Request.URL.Parse(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint '%s': %v", data, err)
+	}
+	if !sameOrigin(req.URL, endpoint) {
+		return "", fmt.Errorf("endpoint '%s' is not on the remote's origin", data)
+	}
+
+	posted, err := p.postInitialize(ctx, endpoint.String())
+	if err != nil {
+		return "", err
+	}
+	_ = posted.Body.Close()
+	if err := checkProbeResponse(posted, http.StatusOK, http.StatusAccepted); err != nil {
+		return "", err
+	}
+
+	return readInitializeResult(events)
+}
+
+func (p *Prober) postInitialize(ctx context.Context, endpoint string) (*http.Response, error) {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      probeRequestID,
+		"method":  "initialize",
+		"params": map[string]any{
+			"protocolVersion": probeProtocolVersion,
+			"capabilities":    map[string]any{},
+			"clientInfo":      map[string]string{"name": probeClientName, "version": buildVersion},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	return resp, nil
+}
+
+// closeSession ends the session the probe opened, so servers do not keep it
+// around until it expires. Failures are of no interest to the probe.
+func (p *Prober) closeSession(ctx context.Context, endpoint, session string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Mcp-Session-Id", session)
+	if resp, err := p.client.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// hasOAuthMetadata reports whether the remote publishes OAuth protected
+// resource metadata with at least one authorization server. The metadata
+// URL is taken from the challenge, falling back to the well-known location,
+// and must be on the remote's origin.
+func (p *Prober) hasOAuthMetadata(ctx context.Context, remoteURL, challenge string) bool {
+	remote, err := url.Parse(remoteURL)
+	if err != nil {
+		return false
+	}
+	metadataURL := remote.ResolveReference(&url.URL{Path: "/.well-known/oauth-protected-resource"})
+	if match := resourceMetadataRegex.FindStringSubmatch(challenge); match != nil {
+		if metadataURL, err = remote.Parse(match[1]); err != nil {
+			return false
+		}
+	}
+	if !sameOrigin(remote, metadataURL) {
+		slog.Warn("Ignoring resource metadata on another origin", "url", remoteURL, "resource_metadata", metadataURL.String())
+		return false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL.String(), nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var metadata struct {
+		AuthorizationServers []string `json:"authorization_servers"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, probeMaxBody)).Decode(&metadata); err != nil {
+		return false
+	}
+	return len(metadata.AuthorizationServers) > 0
+}
+
+// checkProbeResponse turns a 401 with a challenge into an authChallenge and
+// any other unexpected status into an error.
+func checkProbeResponse(resp *http.Response, expected ...int) error {
+	if resp.StatusCode == http.StatusUnauthorized {
+		if challenge := resp.Header.Get("WWW-Authenticate"); challenge != "" {
+			return &authChallenge{header: challenge}
+		}
+		return fmt.Errorf("returned %s without a WWW-Authenticate challenge", resp.Status)
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("returned %s", resp.Status)
+}
+
+// readInitializeResult reads events until the response to the probe's
+// initialize request arrives.
+func readInitializeResult(events *eventReader) (string, error) {
+	for {
+		event, data, err := events.next()
+		if err != nil {
+			return "", err
+		}
+		if event != "message" {
+			continue
+		}
+		var message struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal([]byte(data), &message) != nil || string(message.ID) != fmt.Sprint(probeRequestID) {
+			continue
+		}
+		return parseInitializeResult([]byte(data))
+	}
+}
+
+func parseInitializeResult(message []byte) (string, error) {
+	var response struct {
+		Result *struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(message, &response); err != nil {
+		return "", fmt.Errorf("invalid initialize response: %v", err)
+	}
+	if response.Error != nil {
+		return "", fmt.Errorf("initialize failed with code %d: %s", response.Error.Code, response.Error.Message)
+	}
+	if response.Result == nil || response.Result.ProtocolVersion == "" {
+		return "", fmt.Errorf("initialize response has no protocolVersion")
+	}
+	return response.Result.ProtocolVersion, nil
+}
+
+// eventReader reads server-sent events from a stream.
+type eventReader struct {
+	r *bufio.Reader
+}
+
+func newEventReader(r io.Reader) *eventReader {
+	return &eventReader{r: bufio.NewReader(r)}
+}
+
+// next returns the type and data of the next event. Events without a type
+// are "message" events, as in the EventSource specification.
+func (e *eventReader) next() (string, string, error) {
+	event := ""
+	var data []string
+	for {
+		line, err := e.r.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return "", "", fmt.Errorf("event stream ended before the initialize response")
+			}
+			return "", "", fmt.Errorf("cannot read event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if len(data) == 0 {
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			return event, strings.Join(data, "\n"), nil
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// probeTargets returns the distinct http remotes of every server that is not
+// deleted. Remotes with unresolved URL variables cannot be probed.
+func probeTargets(servers []ServerResponse) []Transport {
+	var targets []Transport
+	seen := make(map[string]bool)
+	for i := range servers {
+		if servers[i].Meta.Official.Status == StatusDeleted {
+			continue
+		}
+		for _, remote := range servers[i].Server.Remotes {
+			if remote.Type != TransportTypeStreamableHTTP && remote.Type != TransportTypeSSE {
+				continue
+			}
+			if seen[remote.URL] || strings.Contains(remote.URL, "{") {
+				continue
+			}
+			seen[remote.URL] = true
+			targets = append(targets, remote)
+		}
+	}
+	return targets
+}
+
+// logProbeResults logs a summary of a probe round and each unreachable
+// remote.
+func logProbeResults(results map[string]RemoteHealth) {
+	unreachable := 0
+	for _, health := range results {
+		if health.Status == HealthUnreachable {
+			unreachable++
+			slog.Warn("Remote is unreachable", "url", health.URL, "error", health.Error)
+		}
+	}
+	slog.Info("Probed remotes", "remote_count", len(results), "unreachable_count", unreachable)
+}
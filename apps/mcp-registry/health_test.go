@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const initializeResult = `{"jsonrpc": "2.0", "id": 1, "result": {"protocolVersion": "2025-03-26", "capabilities": {}, "serverInfo": {"name": "test", "version": "1.0.0"}}}`
+
+// newMCPStandIn serves a streamable-http MCP endpoint at /mcp that answers
+// initialize with handler, and OAuth protected resource metadata at
+// /.well-known/oauth-protected-resource.
+func newMCPStandIn(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", handler)
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, _ *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]any{"resource": "https://example.com/mcp", "authorization_servers": []string{"https://login.example.com"}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProber_StreamableHTTP(t *testing.T) {
+	tests := []struct {
+		name             string
+		handler          http.HandlerFunc
+		expectedStatus   string
+		expectedProtocol string
+		expectedOAuth    bool
+		expectedError    string
+	}{
+		{
+			name: "json response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(initializeResult))
+			},
+			expectedStatus:   HealthReachable,
+			expectedProtocol: "2025-03-26",
+		},
+		{
+			name: "event stream response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", initializeResult)
+			},
+			expectedStatus:   HealthReachable,
+			expectedProtocol: "2025-03-26",
+		},
+		{
+			name: "auth challenge with resource metadata",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="http://`+r.Host+`/.well-known/oauth-protected-resource"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus: HealthAuthRequired,
+			expectedOAuth:  true,
+		},
+		{
+			name: "auth challenge with metadata at the well-known location",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus: HealthAuthRequired,
+			expectedOAuth:  true,
+		},
+		{
+			name: "auth challenge without metadata",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="http://`+r.Host+`/missing"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus: HealthAuthRequired,
+		},
+		{
+			name: "401 without challenge",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			expectedStatus: HealthUnreachable,
+			expectedError:  "without a WWW-Authenticate challenge",
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			expectedStatus: HealthUnreachable,
+			expectedError:  "502",
+		},
+		{
+			name: "json-rpc error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "error": {"code": -32602, "message": "Unsupported protocol version"}}`))
+			},
+			expectedStatus: HealthUnreachable,
+			expectedError:  "Unsupported protocol version",
+		},
+		{
+			name: "not an MCP server",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte("<html></html>"))
+			},
+			expectedStatus: HealthUnreachable,
+			expectedError:  "unexpected initialize response type 'text/html'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMCPStandIn(t, tt.handler)
+
+			health := NewProber(server.Client()).Probe(context.Background(), Transport{Type: TransportTypeStreamableHTTP, URL: server.URL + "/mcp"})
+
+			if health.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s (%s)", tt.expectedStatus, health.Status, health.Error)
+			}
+			if health.ProtocolVersion != tt.expectedProtocol {
+				t.Errorf("expected protocol version %q, got %q", tt.expectedProtocol, health.ProtocolVersion)
+			}
+			if health.OAuthMetadata != tt.expectedOAuth {
+				t.Errorf("expected oauthMetadata=%v, got %v", tt.expectedOAuth, health.OAuthMetadata)
+			}
+			if !strings.Contains(health.Error, tt.expectedError) {
+				t.Errorf("expected error containing %q, got %q", tt.expectedError, health.Error)
+			}
+			if health.CheckedAt.IsZero() {
+				t.Error("expected checkedAt to be set")
+			}
+		})
+	}
+}
+
+func TestProber_InitializeRequest(t *testing.T) {
+	var closed atomic.Bool
+	server := newMCPStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			closed.Store(r.Header.Get("Mcp-Session-Id") == "session-1")
+			return
+		}
+
+		var request struct {
+			Method string `json:"method"`
+			Params struct {
+				ProtocolVersion string `json:"protocolVersion"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "initialize" || request.Params.ProtocolVersion == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			http.Error(w, "not acceptable", http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(initializeResult))
+	})
+
+	health := NewProber(server.Client()).Probe(context.Background(), Transport{Type: TransportTypeStreamableHTTP, URL: server.URL + "/mcp"})
+
+	if health.Status != HealthReachable {
+		t.Fatalf("expected remote to be reachable, got %+v", health)
+	}
+	if !closed.Load() {
+		t.Error("expected the probe session to be closed")
+	}
+}
+
+func TestProber_SSE(t *testing.T) {
+	messages := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": connected\n\nevent: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+
+		select {
+		case message := <-messages:
+			_, _ = fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\": \"2.0\", \"method\": \"notifications/message\"}\n\n")
+			_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", message)
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "1" {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		messages <- initializeResult
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	health := NewProber(server.Client()).Probe(context.Background(), Transport{Type: TransportTypeSSE, URL: server.URL + "/sse"})
+
+	if health.Status != HealthReachable || health.ProtocolVersion != "2025-03-26" {
+		t.Errorf("expected reachable remote with protocol version 2025-03-26, got %+v", health)
+	}
+}
+
+func TestProber_CrossOrigin(t *testing.T) {
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		respondJSON(w, http.StatusOK, map[string]any{"authorization_servers": []string{"https://login.example.com"}})
+	}))
+	defer internal.Close()
+
+	t.Run("resource metadata", func(t *testing.T) {
+		server := newMCPStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+internal.URL+`/metadata"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+
+		health := NewProber(nil).Probe(context.Background(), Transport{Type: TransportTypeStreamableHTTP, URL: server.URL + "/mcp"})
+
+		if health.Status != HealthAuthRequired || health.OAuthMetadata {
+			t.Errorf("expected auth required without OAuth metadata, got %+v", health)
+		}
+	})
+
+	t.Run("sse endpoint", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, "event: endpoint\ndata: %s/messages\n\n", internal.URL)
+		}))
+		defer server.Close()
+
+		health := NewProber(nil).Probe(context.Background(), Transport{Type: TransportTypeSSE, URL: server.URL + "/sse"})
+
+		if health.Status != HealthUnreachable || !strings.Contains(health.Error, "not on the remote's origin") {
+			t.Errorf("expected cross-origin endpoint to be refused, got %+v", health)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		server := httptest.NewServer(http.RedirectHandler(internal.URL+"/mcp", http.StatusTemporaryRedirect))
+		defer server.Close()
+
+		health := NewProber(nil).Probe(context.Background(), Transport{Type: TransportTypeStreamableHTTP, URL: server.URL + "/mcp"})
+
+		if health.Status != HealthUnreachable || !strings.Contains(health.Error, "leaves the remote's origin") {
+			t.Errorf("expected cross-origin redirect to be refused, got %+v", health)
+		}
+	})
+
+	if hits := internalHits.Load(); hits != 0 {
+		t.Errorf("expected no requests to the other origin, got %d", hits)
+	}
+}
+
+func TestProber_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	health := NewProber(nil).Probe(context.Background(), Transport{Type: TransportTypeStreamableHTTP, URL: url + "/mcp"})
+
+	if health.Status != HealthUnreachable || health.Error == "" {
+		t.Errorf("expected unreachable remote with an error, got %+v", health)
+	}
+}
+
+func TestProbeTargets(t *testing.T) {
+	registry := loadTestRegistry(t, `{"servers": [
+		{"name": "io.github.test/alpha", "owner": "team-test", "description": "Alpha", "version": "2.0.0",
+			"remotes": [{"type": "streamable-http", "url": "https://alpha.example.com/mcp"}, {"type": "sse", "url": "https://alpha.example.com/sse"}]},
+		{"name": "io.github.test/alpha", "owner": "team-test", "description": "Alpha", "version": "1.0.0",
+			"remotes": [{"type": "streamable-http", "url": "https://alpha.example.com/mcp"}]},
+		{"name": "io.github.test/beta", "description": "Beta", "version": "1.0.0", "status": "deleted",
+			"remotes": [{"type": "streamable-http", "url": "https://beta.example.com/mcp"}]},
+		{"name": "io.github.test/gamma", "owner": "team-test", "description": "Gamma", "version": "1.0.0",
+			"remotes": [{"type": "streamable-http", "url": "https://gamma.example.com/{tenant}/mcp",
+				"variables": {"tenant": {"description": "Tenant", "isRequired": true}}}]}
+	]}`, time.Now())
+
+	targets := probeTargets(registry.Servers())
+
+	if len(targets) != 2 || targets[0].URL != "https://alpha.example.com/mcp" || targets[1].URL != "https://alpha.example.com/sse" {
+		t.Errorf("expected the distinct remotes of alpha, got %+v", targets)
+	}
+}
+
+func TestRegistry_ProbeRemotes(t *testing.T) {
+	server := newMCPStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(initializeResult))
+	})
+
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, `{"servers": [
+		{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0",
+			"remotes": [{"type": "streamable-http", "url": "`+server.URL+`/mcp"}]},
+		{"name": "io.github.test/server", "description": "Test", "version": "0.9.0", "status": "deleted",
+			"remotes": [{"type": "streamable-http", "url": "`+server.URL+`/mcp"}]}
+	]}`, time.Now().Add(-time.Hour))
+
+	config := testConfig()
+	config.HealthProbeInterval = time.Hour
+	metrics := NewMetrics()
+	registry := NewRegistry(newFileStore(path, config), config, metrics)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+	etag := registry.Snapshot().etag
+	lastModified := registry.Snapshot().modifiedAt.UTC().Format(http.TimeFormat)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.ProbeRemotes(ctx, config.HealthProbeInterval)
+
+	if !waitFor(t, func() bool {
+		s, _ := registry.Find("io.github.test/server", "1.0.0")
+		return s.Meta.Nav != nil && len(s.Meta.Nav.Health) == 1
+	}) {
+		t.Fatal("expected probe results to be served")
+	}
+
+	s, _ := registry.Find("io.github.test/server", "1.0.0")
+	if health := s.Meta.Nav.Health[0]; health.Status != HealthReachable || health.URL != server.URL+"/mcp" {
+		t.Errorf("expected reachable remote, got %+v", health)
+	}
+	if deleted, _ := registry.Find("io.github.test/server", "0.9.0"); deleted.Meta.Nav.Health != nil {
+		t.Errorf("expected no health for a deleted version, got %+v", deleted.Meta.Nav.Health)
+	}
+	if registry.Snapshot().etag == etag {
+		t.Error("expected the etag to change with the probe results")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	w := httptest.NewRecorder()
+	serversListHandler(w, req, registry)
+	if w.Code != http.StatusOK || w.Header().Get("Last-Modified") == lastModified {
+		t.Errorf("expected probe results to move Last-Modified past %s, got %d with %s", lastModified, w.Code, w.Header().Get("Last-Modified"))
+	}
+
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if s, _ := registry.Find("io.github.test/server", "1.0.0"); s.Meta.Nav == nil || len(s.Meta.Nav.Health) != 1 {
+		t.Error("expected probe results to survive a reload")
+	}
+
+	exposition := scrapeMetrics(t, metrics)
+	assertMetric(t, exposition, `mcp_registry_remote_up{name="io.github.test/server",url="`+server.URL+`/mcp"} 1`)
+	assertMetric(t, exposition, `mcp_registry_remote_oauth_metadata{name="io.github.test/server",url="`+server.URL+`/mcp"} 0`)
+}
+
+func TestProbedModifiedAt(t *testing.T) {
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	registry := loadTestRegistry(t, `{"servers": [
+		{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0",
+			"remotes": [{"type": "streamable-http", "url": "https://server.example.com/mcp"}]}
+	]}`, updatedAt)
+	probed := func(health RemoteHealth) map[string]RemoteHealth {
+		health.URL = "https://server.example.com/mcp"
+		return map[string]RemoteHealth{health.URL: health}
+	}
+	current := newRegistrySnapshot(registry.served, updatedAt, probed(RemoteHealth{
+		Status: HealthReachable, LatencyMs: 42, CheckedAt: updatedAt,
+	}))
+
+	tests := []struct {
+		name        string
+		health      RemoteHealth
+		expectedNew bool
+	}{
+		{"new latency and time", RemoteHealth{Status: HealthReachable, LatencyMs: 900, CheckedAt: updatedAt.Add(time.Minute)}, false},
+		{"new status", RemoteHealth{Status: HealthUnreachable, Error: "request failed", LatencyMs: 42, CheckedAt: updatedAt}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := newRegistrySnapshot(registry.served, updatedAt, probed(tt.health))
+			modifiedAt := probedModifiedAt(current, snapshot)
+
+			if changed := snapshot.etag != current.etag; changed != tt.expectedNew {
+				t.Errorf("expected etag change %v, got %v", tt.expectedNew, changed)
+			}
+			if moved := modifiedAt.After(current.modifiedAt); moved != tt.expectedNew {
+				t.Errorf("expected Last-Modified to move %v, got %s after %s", tt.expectedNew, modifiedAt, current.modifiedAt)
+			}
+		})
+	}
+}
@@ -1,14 +1,33 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
-	config := loadConfig()
+	config, err := loadConfig()
+	if err != nil {
+		slog.Error("Server startup failed - invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate", "lint":
+			os.Exit(runValidate(os.Args[2:], config, os.Stdout, os.Stderr))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command '%s', available commands: validate\n", os.Args[1])
+			os.Exit(exitUsage)
+		}
+	}
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: config.LogLevel,
@@ -21,32 +40,100 @@ func main() {
 		"domain_external", config.DomainExternal,
 		"log_level", config.LogLevel.String(),
 		"logged_endpoints", getEndpointsList(config.LoggedEndpoints),
+		"allowlist_path", config.AllowlistPath,
+		"allowlist_public_key", config.PublicKeyFile,
+		"database", config.DatabaseURL != "",
+		"reload_interval", config.ReloadInterval.String(),
+		"upstream_registry", config.UpstreamURL,
+		"health_probe_interval", config.HealthProbeInterval.String(),
+		"template_variables", config.variableNames(),
 	)
 
-	if err := validateAllowListFile(); err != nil {
-		slog.Error("Server startup failed - invalid allowlist.json", "error", err)
+	metrics := NewMetrics()
+
+	store, err := openStore(context.Background(), config)
+	if err != nil {
+		slog.Error("Server startup failed - cannot open store", "error", err)
 		os.Exit(1)
 	}
 
-	http.HandleFunc("/health", loggingMiddleware(config, healthHandler))
-	http.HandleFunc("/ready", loggingMiddleware(config, readyHandler))
-	http.HandleFunc("/metrics", loggingMiddleware(config, metricsHandler))
-	http.HandleFunc("/v0.1/servers", loggingMiddleware(config, makeServersListHandler(config)))
-	http.HandleFunc("/v0.1/servers/", loggingMiddleware(config, makeServerVersionHandler(config)))
-	http.HandleFunc("/", loggingMiddleware(config, rootHandler))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
 
-	slog.Info("Allowlist validation passed - registry contains valid server configurations")
-	slog.Info("Server listening", "port", config.Port)
+	registry := NewRegistry(store, config, metrics)
+	auth, err := NewAuthenticator(config)
+	if err != nil {
+		slog.Error("Server startup failed - invalid admin API configuration", "error", err)
+		os.Exit(1)
+	}
+	if auth != nil {
+		slog.Info("Admin API enabled", "static_token", config.AdminToken != "", "oidc_issuer", config.OIDCIssuer, "admin_groups", config.AdminGroups)
+	}
 
 	server := &http.Server{
-		Addr:         ":" + config.Port,
+		Handler:      newRouter(config, registry, metrics, auth),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	listener, err := net.Listen("tcp", ":"+config.Port)
+	if err != nil {
+		slog.Error("Server startup failed - cannot listen", "port", config.Port, "error", err)
+		os.Exit(1)
+	}
+
+	// Serve before loading, so liveness is answered while /ready reports
+	// that the allowlist is not loaded yet.
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server, listener, registry, config.ShutdownDelay, config.ShutdownTimeout)
+	}()
+	slog.Info("Server listening", "port", config.Port)
+
+	if err := registry.Load(); err != nil {
+		slog.Error("Server startup failed - invalid allowlist", "store", store.String(), "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Allowlist validation passed - registry contains valid server configurations")
 
-	if err := server.ListenAndServe(); err != nil {
+	go registry.Watch(ctx, config.ReloadInterval)
+	go registry.Mirror(ctx, config.UpstreamSync)
+	go registry.ProbeRemotes(ctx, config.HealthProbeInterval)
+
+	err = <-served
+	stop()
+	if err != nil {
 		slog.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
+	slog.Info("Server stopped")
+}
+
+// openStore returns the PostgreSQL store when DATABASE_URL is set, seeded
+// from the allowlist file if the database is empty, and the allowlist file
+// store otherwise. A signed allowlist and mirroring are only supported with
+// the file.
+func openStore(ctx context.Context, config *Config) (RegistryStore, error) {
+	if config.DatabaseURL == "" {
+		return newFileStore(config.AllowlistPath, config), nil
+	}
+	if config.PublicKeyFile != "" {
+		return nil, fmt.Errorf("ALLOWLIST_PUBLIC_KEY requires the allowlist file store, since the database cannot be signed")
+	}
+	if config.UpstreamURL != "" {
+		return nil, fmt.Errorf("UPSTREAM_REGISTRY_URL requires the allowlist file store, since the database has no mirror section")
+	}
+
+	store, err := newPostgresStore(ctx, config.DatabaseURL, config)
+	if err != nil {
+		return nil, err
+	}
+	imported, err := store.Import(ctx, config.AllowlistPath)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("cannot import %s into the database: %w", config.AllowlistPath, err)
+	}
+	if imported > 0 {
+		slog.Info("Imported allowlist into empty database", "file", config.AllowlistPath, "server_count", imported)
+	}
+	return store, nil
 }
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// launch is how a client connects to a server, or starts it locally,
+// independent of the format the client is configured in. A local server is
+// started with Command and RunArgs, its runtime arguments, Args, which name
+// the package, and then its package arguments.
+type launch struct {
+	Type             string
+	URL              string
+	Variables        map[string]Input
+	Headers          []KeyValueInput
+	Command          string
+	RunArgs          []string
+	RuntimeArguments []Argument
+	Args             []string
+	PackageArguments []Argument
+	Env              []KeyValueInput
+}
+
+// vscodeConfig is the mcp.json format VS Code reads MCP servers from.
+type vscodeConfig struct {
+	Inputs  []vscodeInput           `json:"inputs,omitempty"`
+	Servers map[string]vscodeServer `json:"servers"`
+}
+
+type vscodeServer struct {
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+// vscodeInput is a value VS Code prompts for the first time the server
+// starts, referenced as ${input:id}.
+type vscodeInput struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	Password    bool   `json:"password,omitempty"`
+}
+
+// vscodeInstall is the server a vscode:mcp/install link adds.
+type vscodeInstall struct {
+	Name string `json:"name"`
+	vscodeServer
+	Inputs []vscodeInput `json:"inputs,omitempty"`
+}
+
+// mcpServersConfig is the mcpServers format read by the Copilot CLI from
+// ~/.copilot/mcp-config.json, and by most other clients from mcp.json.
+type mcpServersConfig struct {
+	MCPServers map[string]mcpServersEntry `json:"mcpServers"`
+}
+
+type mcpServersEntry struct {
+	Type    string            `json:"type,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Tools   []string          `json:"tools,omitempty"`
+}
+
+// newLaunch returns how to use a server through its first remote, or its
+// first stdio package that can be run with a well-known command. Packages
+// that serve HTTP must be started before a client connects to them, which
+// client configs cannot express, so they are skipped. It reports false if
+// the server has neither.
+func newLaunch(s *ServerJSON) (launch, bool) {
+	for _, remote := range s.Remotes {
+		if remote.Type == TransportTypeStreamableHTTP || remote.Type == TransportTypeSSE {
+			return launch{Type: remote.Type, URL: remote.URL, Variables: remote.Variables, Headers: remote.Headers}, true
+		}
+	}
+
+	for i := range s.Packages {
+		pkg := &s.Packages[i]
+		if pkg.Transport.Type != "" && pkg.Transport.Type != TransportTypeStdio {
+			continue
+		}
+		command, runArgs, args, ok := packageCommand(pkg)
+		if !ok {
+			continue
+		}
+		return launch{
+			Type:             TransportTypeStdio,
+			Command:          command,
+			RunArgs:          runArgs,
+			RuntimeArguments: pkg.RuntimeArguments,
+			Args:             args,
+			PackageArguments: pkg.PackageArguments,
+			Env:              pkg.EnvironmentVariables,
+		}, true
+	}
+
+	return launch{}, false
+}
+
+// packageCommand returns the command that downloads and runs a package, the
+// arguments that go before the runtime arguments, and the arguments that
+// name the package. The runtime hint of the package replaces the well-known
+// command, which it must take the same arguments as. mcpb bundles need a
+// client that installs them, so they have no command.
+func packageCommand(pkg *Package) (string, []string, []string, bool) {
+	var command string
+	var runArgs, args []string
+	switch pkg.RegistryType {
+	case RegistryTypeNPM:
+		command, args = "npx", []string{"-y", pkg.Identifier + "@" + pkg.Version}
+	case RegistryTypePyPI:
+		command, args = "uvx", []string{pkg.Identifier + "==" + pkg.Version}
+	case RegistryTypeNuGet:
+		command, args = "dnx", []string{pkg.Identifier + "@" + pkg.Version, "--yes"}
+	case RegistryTypeOCI:
+		image := pkg.Identifier
+		if pkg.Version != "" && !strings.Contains(image, ":") {
+			image += ":" + pkg.Version
+		}
+		command, runArgs, args = "docker", []string{"run", "-i", "--rm"}, []string{image}
+		for _, env := range pkg.EnvironmentVariables {
+			runArgs = append(runArgs, "-e", env.Name)
+		}
+	default:
+		return "", nil, nil, false
+	}
+	if pkg.RuntimeHint != "" {
+		command = pkg.RuntimeHint
+	}
+	return command, runArgs, args, true
+}
+
+// launchArgs returns the arguments a local server is started with, with the
+// values of its runtime and package arguments filled in.
+func launchArgs(l *launch, reference func(KeyValueInput) string) []string {
+	args := appendArguments(slices.Clone(l.RunArgs), l.RuntimeArguments, reference)
+	args = append(args, l.Args...)
+	return appendArguments(args, l.PackageArguments, reference)
+}
+
+// appendArguments appends command line arguments to args. A positional
+// argument is its value, and a named argument its name followed by its
+// value, if it has one. Positional arguments without a value are left out.
+func appendArguments(args []string, arguments []Argument, reference func(KeyValueInput) string) []string {
+	for _, argument := range arguments {
+		input := KeyValueInput{Name: argumentName(&argument), Input: argument.Input, Variables: argument.Variables}
+		value, ok := inputValue(input, reference)
+		switch {
+		case argument.Type == ArgumentTypeNamed && ok:
+			args = append(args, argument.Name, value)
+		case argument.Type == ArgumentTypeNamed:
+			args = append(args, argument.Name)
+		case ok:
+			args = append(args, value)
+		}
+	}
+	return args
+}
+
+// argumentName returns the name the value of an argument is referred to by:
+// the name of a named argument without its leading dashes, or the value
+// hint of a positional argument.
+func argumentName(argument *Argument) string {
+	if argument.Type == ArgumentTypeNamed {
+		return strings.TrimLeft(argument.Name, "-")
+	}
+	if argument.ValueHint != "" {
+		return argument.ValueHint
+	}
+	return argument.Name
+}
+
+// launchEnv returns the environment a server is started with.
+func launchEnv(l *launch, reference func(KeyValueInput) string) map[string]string {
+	return inputValues(l.Env, reference)
+}
+
+// launchHeaders returns the headers a client sends to a remote server.
+func launchHeaders(l *launch, reference func(KeyValueInput) string) map[string]string {
+	return inputValues(l.Headers, reference)
+}
+
+// launchURL returns the URL of a remote server with its variables filled in.
+func launchURL(l *launch, reference func(KeyValueInput) string) string {
+	return fillVariables(l.URL, l.Variables, reference)
+}
+
+// inputValues returns the values of named inputs, such as environment
+// variables or headers, leaving out inputs without a value.
+func inputValues(inputs []KeyValueInput, reference func(KeyValueInput) string) map[string]string {
+	values := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		if value, ok := inputValue(input, reference); ok {
+			values[input.Name] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// inputValue returns the value of an input. Fixed values are set directly,
+// with their {name} variables filled in, and secrets and required values
+// are taken from reference, which returns how the client refers to a value
+// the user provides. Optional values fall back to their default. It reports
+// false if the input has no value.
+func inputValue(input KeyValueInput, reference func(KeyValueInput) string) (string, bool) {
+	switch {
+	case input.Value != "":
+		return fillVariables(input.Value, input.Variables, reference), true
+	case input.IsSecret || input.IsRequired:
+		return reference(input), true
+	case input.Default != "":
+		return input.Default, true
+	}
+	return "", false
+}
+
+// fillVariables replaces the {name} placeholders in s with the values of
+// variables. Placeholders without a variable are left in place.
+func fillVariables(s string, variables map[string]Input, reference func(KeyValueInput) string) string {
+	if len(variables) == 0 {
+		return s
+	}
+	return packageVarRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[1 : len(match)-1]
+		variable, ok := variables[name]
+		if !ok {
+			return match
+		}
+		value, _ := inputValue(KeyValueInput{Name: name, Input: variable}, reference)
+		return value
+	})
+}
+
+// newVSCodeConfig returns the VS Code mcp.json for a server. Values the user
+// provides are prompted for as inputs. It reports false if the server cannot
+// be configured.
+func newVSCodeConfig(s *ServerJSON) (vscodeConfig, bool) {
+	l, ok := newLaunch(s)
+	if !ok {
+		return vscodeConfig{}, false
+	}
+
+	server, inputs := newVSCodeServer(&l)
+	return vscodeConfig{
+		Inputs:  inputs,
+		Servers: map[string]vscodeServer{serverConfigKey(s.Name): server},
+	}, true
+}
+
+func newVSCodeServer(l *launch) (vscodeServer, []vscodeInput) {
+	var inputs []vscodeInput
+	reference := func(variable KeyValueInput) string {
+		id := strings.ToLower(variable.Name)
+		if !slices.ContainsFunc(inputs, func(input vscodeInput) bool { return input.ID == id }) {
+			inputs = append(inputs, vscodeInput{Type: "promptString", ID: id, Description: variable.Description, Password: variable.IsSecret})
+		}
+		return "${input:" + id + "}"
+	}
+
+	var server vscodeServer
+	switch l.Type {
+	case TransportTypeStreamableHTTP:
+		server = vscodeServer{Type: "http", URL: launchURL(l, reference), Headers: launchHeaders(l, reference)}
+	case TransportTypeSSE:
+		server = vscodeServer{Type: "sse", URL: launchURL(l, reference), Headers: launchHeaders(l, reference)}
+	default:
+		server = vscodeServer{Type: "stdio", Command: l.Command, Args: launchArgs(l, reference), Env: launchEnv(l, reference)}
+	}
+	return server, inputs
+}
+
+// vscodeInstallURL returns a link that adds a server to VS Code, using the
+// given URL scheme: "vscode" or "vscode-insiders".
+func vscodeInstallURL(scheme string, s *ServerJSON) (string, bool) {
+	l, ok := newLaunch(s)
+	if !ok {
+		return "", false
+	}
+
+	server, inputs := newVSCodeServer(&l)
+	data, err := json.Marshal(vscodeInstall{Name: serverConfigKey(s.Name), vscodeServer: server, Inputs: inputs})
+	if err != nil {
+		return "", false
+	}
+	// VS Code decodes the query like encodeURIComponent encodes it, which
+	// leaves no "+" for spaces.
+	return scheme + ":mcp/install?" + strings.ReplaceAll(url.QueryEscape(string(data)), "+", "%20"), true
+}
+
+// newCopilotCLIConfig returns the ~/.copilot/mcp-config.json for a server.
+// The Copilot CLI cannot prompt, so values the user provides are read from
+// environment variables named like the value.
+func newCopilotCLIConfig(s *ServerJSON) (mcpServersConfig, bool) {
+	l, ok := newLaunch(s)
+	if !ok {
+		return mcpServersConfig{}, false
+	}
+
+	entry := mcpServersEntry{Tools: []string{"*"}}
+	switch l.Type {
+	case TransportTypeStreamableHTTP:
+		entry.Type, entry.URL = "http", launchURL(&l, environmentReference)
+		entry.Headers = launchHeaders(&l, environmentReference)
+	case TransportTypeSSE:
+		entry.Type, entry.URL = "sse", launchURL(&l, environmentReference)
+		entry.Headers = launchHeaders(&l, environmentReference)
+	default:
+		entry.Type, entry.Command, entry.Args = "local", l.Command, launchArgs(&l, environmentReference)
+		entry.Env = launchEnv(&l, environmentReference)
+	}
+	return mcpServersConfig{MCPServers: map[string]mcpServersEntry{serverConfigKey(s.Name): entry}}, true
+}
+
+// newGenericConfig returns the mcpServers mcp.json most other clients read.
+// Local servers have no type, which clients take to mean stdio, and values
+// the user provides are read from environment variables named like the value.
+func newGenericConfig(s *ServerJSON) (mcpServersConfig, bool) {
+	l, ok := newLaunch(s)
+	if !ok {
+		return mcpServersConfig{}, false
+	}
+
+	var entry mcpServersEntry
+	switch l.Type {
+	case TransportTypeStreamableHTTP:
+		entry.Type, entry.URL = "http", launchURL(&l, environmentReference)
+		entry.Headers = launchHeaders(&l, environmentReference)
+	case TransportTypeSSE:
+		entry.Type, entry.URL = "sse", launchURL(&l, environmentReference)
+		entry.Headers = launchHeaders(&l, environmentReference)
+	default:
+		entry.Command, entry.Args = l.Command, launchArgs(&l, environmentReference)
+		entry.Env = launchEnv(&l, environmentReference)
+	}
+	return mcpServersConfig{MCPServers: map[string]mcpServersEntry{serverConfigKey(s.Name): entry}}, true
+}
+
+// envNameRegex matches the characters that cannot be part of an environment
+// variable name.
+var envNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// environmentReference refers to the environment variable named like a
+// value, with the characters envNameRegex matches, such as the dashes in
+// header names, replaced by underscores.
+func environmentReference(variable KeyValueInput) string {
+	return "${" + envNameRegex.ReplaceAllString(variable.Name, "_") + "}"
+}
+
+// newClientConfig returns the configuration of a server for client, with
+// install links for clients that have them. It reports false if the server
+// cannot be configured.
+func newClientConfig(s *ServerJSON, client string) (ClientConfigResponse, bool) {
+	response := ClientConfigResponse{Client: client, Name: serverConfigKey(s.Name)}
+
+	var ok bool
+	switch client {
+	case ClientVSCode:
+		response.Config, ok = newVSCodeConfig(s)
+		if ok {
+			response.InstallURL, _ = vscodeInstallURL("vscode", s)
+			response.InsidersInstallURL, _ = vscodeInstallURL("vscode-insiders", s)
+		}
+	case ClientCopilotCLI:
+		response.Config, ok = newCopilotCLIConfig(s)
+	case ClientGeneric:
+		response.Config, ok = newGenericConfig(s)
+	}
+	return response, ok
+}
+
+// serverConfigKey returns the key a server is configured under in client
+// configs, which is the part of the name after the namespace.
+func serverConfigKey(name string) string {
+	_, key, found := strings.Cut(name, "/")
+	if !found {
+		return name
+	}
+	return key
+}
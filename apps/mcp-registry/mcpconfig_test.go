@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewVSCodeConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		server         ServerJSON
+		expectedServer vscodeServer
+		expectedInputs []vscodeInput
+		expectedOK     bool
+	}{
+		{
+			name: "streamable-http remote",
+			server: ServerJSON{Name: "io.github.navikt/remote", Remotes: []Transport{
+				{Type: TransportTypeStreamableHTTP, URL: "https://remote.intern.nav.no/mcp"},
+			}},
+			expectedServer: vscodeServer{Type: "http", URL: "https://remote.intern.nav.no/mcp"},
+			expectedOK:     true,
+		},
+		{
+			name: "sse remote",
+			server: ServerJSON{Name: "io.github.navikt/remote", Remotes: []Transport{
+				{Type: TransportTypeSSE, URL: "https://remote.intern.nav.no/sse"},
+			}},
+			expectedServer: vscodeServer{Type: "sse", URL: "https://remote.intern.nav.no/sse"},
+			expectedOK:     true,
+		},
+		{
+			name: "remote with headers and url variables",
+			server: ServerJSON{Name: "io.github.navikt/remote", Remotes: []Transport{{
+				Type: TransportTypeStreamableHTTP, URL: "https://remote.intern.nav.no/{tenant}/mcp",
+				Variables: map[string]Input{"tenant": {Description: "Tenant", IsRequired: true}},
+				Headers: []KeyValueInput{
+					{Name: "X-Nav-Team", Input: Input{Description: "Your team", IsRequired: true}},
+					{Name: "Authorization", Input: Input{Value: "Bearer {token}"}, Variables: map[string]Input{"token": {Description: "Token", IsSecret: true}}},
+					{Name: "X-Optional"},
+				},
+			}}},
+			expectedServer: vscodeServer{Type: "http", URL: "https://remote.intern.nav.no/${input:tenant}/mcp",
+				Headers: map[string]string{"X-Nav-Team": "${input:x-nav-team}", "Authorization": "Bearer ${input:token}"}},
+			expectedInputs: []vscodeInput{
+				{Type: "promptString", ID: "tenant", Description: "Tenant"},
+				{Type: "promptString", ID: "x-nav-team", Description: "Your team"},
+				{Type: "promptString", ID: "token", Description: "Token", Password: true},
+			},
+			expectedOK: true,
+		},
+		{
+			name: "npm package with secret",
+			server: ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{{
+				RegistryType: RegistryTypeNPM, Identifier: "@navikt/npm-mcp", Version: "1.2.3",
+				EnvironmentVariables: []KeyValueInput{
+					{Name: "GITHUB_TOKEN", Input: Input{Description: "GitHub token", IsRequired: true, IsSecret: true}},
+					{Name: "LOG_LEVEL", Input: Input{Default: "info"}},
+					{Name: "OPTIONAL"},
+				},
+			}}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "npx", Args: []string{"-y", "@navikt/npm-mcp@1.2.3"},
+				Env: map[string]string{"GITHUB_TOKEN": "${input:github_token}", "LOG_LEVEL": "info"}},
+			expectedInputs: []vscodeInput{{Type: "promptString", ID: "github_token", Description: "GitHub token", Password: true}},
+			expectedOK:     true,
+		},
+		{
+			name: "pypi package",
+			server: ServerJSON{Name: "io.github.navikt/pypi", Packages: []Package{
+				{RegistryType: RegistryTypePyPI, Identifier: "nav-mcp", Version: "0.4.0"},
+			}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "uvx", Args: []string{"nav-mcp==0.4.0"}},
+			expectedOK:     true,
+		},
+		{
+			name: "oci package",
+			server: ServerJSON{Name: "io.github.navikt/oci", Packages: []Package{{
+				RegistryType: RegistryTypeOCI, Identifier: "ghcr.io/navikt/oci-mcp:1.0.0",
+				EnvironmentVariables: []KeyValueInput{{Name: "TOKEN", Input: Input{Value: "fixed"}}},
+			}}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "docker", Args: []string{"run", "-i", "--rm", "-e", "TOKEN", "ghcr.io/navikt/oci-mcp:1.0.0"},
+				Env: map[string]string{"TOKEN": "fixed"}},
+			expectedOK: true,
+		},
+		{
+			name: "package arguments",
+			server: ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{{
+				RegistryType: RegistryTypeNPM, Identifier: "@navikt/npm-mcp", Version: "1.2.3",
+				PackageArguments: []Argument{
+					{Type: ArgumentTypeNamed, Name: "--port", Input: Input{Value: "9000"}},
+					{Type: ArgumentTypeNamed, Name: "--read-only"},
+					{Type: ArgumentTypePositional, ValueHint: "workspace", Input: Input{Description: "Workspace", IsRequired: true}},
+					{Type: ArgumentTypePositional, ValueHint: "optional"},
+				},
+			}}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "npx",
+				Args: []string{"-y", "@navikt/npm-mcp@1.2.3", "--port", "9000", "--read-only", "${input:workspace}"}},
+			expectedInputs: []vscodeInput{{Type: "promptString", ID: "workspace", Description: "Workspace"}},
+			expectedOK:     true,
+		},
+		{
+			name: "runtime hint and arguments",
+			server: ServerJSON{Name: "io.github.navikt/oci", Packages: []Package{{
+				RegistryType: RegistryTypeOCI, Identifier: "ghcr.io/navikt/oci-mcp", Version: "1.0.0", RuntimeHint: "podman",
+				EnvironmentVariables: []KeyValueInput{{Name: "TOKEN", Input: Input{Value: "fixed"}}},
+				RuntimeArguments: []Argument{{Type: ArgumentTypeNamed, Name: "--mount", Input: Input{Value: "type=bind,src={source},dst=/data"},
+					Variables: map[string]Input{"source": {Description: "Directory to mount", IsRequired: true}}}},
+				PackageArguments: []Argument{{Type: ArgumentTypePositional, ValueHint: "mode", Input: Input{Default: "stdio"}}},
+			}}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "podman",
+				Args: []string{"run", "-i", "--rm", "-e", "TOKEN", "--mount", "type=bind,src=${input:source},dst=/data", "ghcr.io/navikt/oci-mcp:1.0.0", "stdio"},
+				Env:  map[string]string{"TOKEN": "fixed"}},
+			expectedInputs: []vscodeInput{{Type: "promptString", ID: "source", Description: "Directory to mount"}},
+			expectedOK:     true,
+		},
+		{
+			name: "http package skipped",
+			server: ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{
+				{RegistryType: RegistryTypeNPM, Identifier: "@navikt/http-mcp", Version: "1.0.0",
+					Transport: Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:8080/mcp"}},
+				{RegistryType: RegistryTypePyPI, Identifier: "nav-mcp", Version: "0.4.0", Transport: Transport{Type: TransportTypeStdio}},
+			}},
+			expectedServer: vscodeServer{Type: "stdio", Command: "uvx", Args: []string{"nav-mcp==0.4.0"}},
+			expectedOK:     true,
+		},
+		{
+			name: "only sse packages",
+			server: ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{
+				{RegistryType: RegistryTypeNPM, Identifier: "@navikt/sse-mcp", Version: "1.0.0",
+					Transport: Transport{Type: TransportTypeSSE, URL: "http://localhost:8080/sse"}},
+			}},
+		},
+		{
+			name: "mcpb only",
+			server: ServerJSON{Name: "io.github.navikt/bundle", Packages: []Package{
+				{RegistryType: RegistryTypeMCPB, Identifier: "https://example.com/bundle.mcpb"},
+			}},
+		},
+		{
+			name:   "nothing to configure",
+			server: ServerJSON{Name: "io.github.navikt/empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, ok := newVSCodeConfig(&tt.server)
+			if ok != tt.expectedOK {
+				t.Fatalf("expected ok=%v, got %v", tt.expectedOK, ok)
+			}
+			if !ok {
+				return
+			}
+
+			key := serverConfigKey(tt.server.Name)
+			if !reflect.DeepEqual(config.Servers[key], tt.expectedServer) {
+				t.Errorf("expected server %+v, got %+v", tt.expectedServer, config.Servers[key])
+			}
+			if !reflect.DeepEqual(config.Inputs, tt.expectedInputs) {
+				t.Errorf("expected inputs %+v, got %+v", tt.expectedInputs, config.Inputs)
+			}
+		})
+	}
+}
+
+func TestNewClientConfig(t *testing.T) {
+	remote := ServerJSON{Name: "io.github.navikt/remote", Remotes: []Transport{
+		{Type: TransportTypeStreamableHTTP, URL: "https://remote.intern.nav.no/mcp"},
+	}}
+	npm := ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{{
+		RegistryType: RegistryTypeNPM, Identifier: "@navikt/npm-mcp", Version: "1.2.3",
+		EnvironmentVariables: []KeyValueInput{
+			{Name: "GITHUB_TOKEN", Input: Input{Description: "GitHub token", IsSecret: true}},
+			{Name: "LOG_LEVEL", Input: Input{Default: "info"}},
+		},
+	}}}
+	arguments := ServerJSON{Name: "io.github.navikt/arguments", Packages: []Package{{
+		RegistryType: RegistryTypeNPM, Identifier: "@x/y", Version: "1.2.3",
+		PackageArguments: []Argument{
+			{Type: ArgumentTypeNamed, Name: "--port", Input: Input{Value: "9000"}},
+			{Type: ArgumentTypeNamed, Name: "--api-key", Input: Input{IsSecret: true}},
+		},
+	}}}
+	headers := ServerJSON{Name: "io.github.navikt/headers", Remotes: []Transport{{
+		Type: TransportTypeSSE, URL: "https://headers.intern.nav.no/sse",
+		Headers: []KeyValueInput{{Name: "X-Nav-Team", Input: Input{IsRequired: true}}, {Name: "X-Client", Input: Input{Value: "copilot"}}},
+	}}}
+
+	tests := []struct {
+		name     string
+		server   ServerJSON
+		client   string
+		expected string
+	}{
+		{
+			name:     "copilot cli remote",
+			server:   remote,
+			client:   ClientCopilotCLI,
+			expected: `{"mcpServers":{"remote":{"type":"http","url":"https://remote.intern.nav.no/mcp","tools":["*"]}}}`,
+		},
+		{
+			name:     "copilot cli package",
+			server:   npm,
+			client:   ClientCopilotCLI,
+			expected: `{"mcpServers":{"npm":{"type":"local","command":"npx","args":["-y","@navikt/npm-mcp@1.2.3"],"env":{"GITHUB_TOKEN":"${GITHUB_TOKEN}","LOG_LEVEL":"info"},"tools":["*"]}}}`,
+		},
+		{
+			name:     "copilot cli remote with headers",
+			server:   headers,
+			client:   ClientCopilotCLI,
+			expected: `{"mcpServers":{"headers":{"type":"sse","url":"https://headers.intern.nav.no/sse","headers":{"X-Client":"copilot","X-Nav-Team":"${X_Nav_Team}"},"tools":["*"]}}}`,
+		},
+		{
+			name:     "generic remote with headers",
+			server:   headers,
+			client:   ClientGeneric,
+			expected: `{"mcpServers":{"headers":{"type":"sse","url":"https://headers.intern.nav.no/sse","headers":{"X-Client":"copilot","X-Nav-Team":"${X_Nav_Team}"}}}}`,
+		},
+		{
+			name:     "generic remote",
+			server:   remote,
+			client:   ClientGeneric,
+			expected: `{"mcpServers":{"remote":{"type":"http","url":"https://remote.intern.nav.no/mcp"}}}`,
+		},
+		{
+			name:     "generic package",
+			server:   npm,
+			client:   ClientGeneric,
+			expected: `{"mcpServers":{"npm":{"command":"npx","args":["-y","@navikt/npm-mcp@1.2.3"],"env":{"GITHUB_TOKEN":"${GITHUB_TOKEN}","LOG_LEVEL":"info"}}}}`,
+		},
+		{
+			name:     "generic package arguments",
+			server:   arguments,
+			client:   ClientGeneric,
+			expected: `{"mcpServers":{"arguments":{"command":"npx","args":["-y","@x/y@1.2.3","--port","9000","--api-key","${api_key}"]}}}`,
+		},
+		{
+			name:     "copilot cli package arguments",
+			server:   arguments,
+			client:   ClientCopilotCLI,
+			expected: `{"mcpServers":{"arguments":{"type":"local","command":"npx","args":["-y","@x/y@1.2.3","--port","9000","--api-key","${api_key}"],"tools":["*"]}}}`,
+		},
+		{
+			name:     "vscode package arguments",
+			server:   arguments,
+			client:   ClientVSCode,
+			expected: `{"inputs":[{"type":"promptString","id":"api-key","password":true}],"servers":{"arguments":{"type":"stdio","command":"npx","args":["-y","@x/y@1.2.3","--port","9000","--api-key","${input:api-key}"]}}}`,
+		},
+		{
+			name:     "vscode package",
+			server:   npm,
+			client:   ClientVSCode,
+			expected: `{"inputs":[{"type":"promptString","id":"github_token","description":"GitHub token","password":true}],"servers":{"npm":{"type":"stdio","command":"npx","args":["-y","@navikt/npm-mcp@1.2.3"],"env":{"GITHUB_TOKEN":"${input:github_token}","LOG_LEVEL":"info"}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := newClientConfig(&tt.server, tt.client)
+			if !ok {
+				t.Fatal("expected server to be configurable")
+			}
+			data, err := json.Marshal(response.Config)
+			if err != nil {
+				t.Fatalf("failed to marshal config: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected config\n%s\ngot\n%s", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestVSCodeInstallURL(t *testing.T) {
+	server := ServerJSON{Name: "io.github.navikt/npm", Packages: []Package{{
+		RegistryType: RegistryTypeNPM, Identifier: "@navikt/npm-mcp", Version: "1.2.3",
+		EnvironmentVariables: []KeyValueInput{{Name: "API_KEY", Input: Input{Description: "API key for Nav", IsRequired: true}}},
+	}}}
+
+	installURL, ok := vscodeInstallURL("vscode-insiders", &server)
+	if !ok {
+		t.Fatal("expected install link")
+	}
+	query, found := strings.CutPrefix(installURL, "vscode-insiders:mcp/install?")
+	if !found {
+		t.Fatalf("expected vscode-insiders:mcp/install link, got %s", installURL)
+	}
+	if strings.ContainsAny(query, "+ ") {
+		t.Errorf("expected spaces to be percent-encoded, got %s", query)
+	}
+
+	decoded, err := url.PathUnescape(query)
+	if err != nil {
+		t.Fatalf("failed to decode link: %v", err)
+	}
+	expected := `{"name":"npm","type":"stdio","command":"npx","args":["-y","@navikt/npm-mcp@1.2.3"],"env":{"API_KEY":"${input:api_key}"},"inputs":[{"type":"promptString","id":"api_key","description":"API key for Nav"}]}`
+	if decoded != expected {
+		t.Errorf("expected link to install\n%s\ngot\n%s", expected, decoded)
+	}
+
+	if _, ok := vscodeInstallURL("vscode", &ServerJSON{Name: "io.github.navikt/empty"}); ok {
+		t.Error("expected no install link for a server without remotes or packages")
+	}
+}
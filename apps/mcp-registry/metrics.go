@@ -0,0 +1,316 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// knownNameRetention is how long a server name is still counted by name in
+// mcp_registry_server_not_found_total after it was last served, so lookups
+// of a removed server can be told apart from other unknown names.
+const knownNameRetention = 24 * time.Hour
+
+// unknownServerName is the label value of lookups for names the registry has
+// not recently served.
+const unknownServerName = "unknown"
+
+// Metrics holds the Prometheus collectors for the registry. A nil *Metrics is
+// valid and records nothing.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	notFound        *prometheus.CounterVec
+	servers         *prometheus.GaugeVec
+	loadTimestamp   prometheus.Gauge
+	reloadFailures  prometheus.Counter
+	mirrored        prometheus.Gauge
+	syncTimestamp   prometheus.Gauge
+	syncFailures    prometheus.Counter
+	remoteUp        *prometheus.GaugeVec
+	remoteLatency   *prometheus.GaugeVec
+	remoteOAuth     *prometheus.GaugeVec
+	probeTimestamp  prometheus.Gauge
+	expiredReviews  prometheus.GaugeFunc
+
+	// reviewExpiries holds when the reviews of the served high-risk servers
+	// expire, so expired reviews are counted at scrape time, not only when
+	// the allowlist is loaded.
+	reviewExpiries atomic.Pointer[[]time.Time]
+
+	// knownNames holds when each server name was last in a loaded snapshot.
+	// Only these names are used as label values, which bounds the label
+	// cardinality by the size of the registry.
+	knownNamesMu sync.Mutex
+	knownNames   map[string]time.Time
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry:   prometheus.NewRegistry(),
+		knownNames: make(map[string]time.Time),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_registry_requests_total",
+			Help: "Total number of HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_registry_request_duration_seconds",
+			Help:    "HTTP request latency by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		notFound: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_registry_server_not_found_total",
+			Help: "Total number of lookups for server versions that are not in the registry, by requested name, or unknown for names the registry has not recently served.",
+		}, []string{"name"}),
+		servers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mcp_registry_servers",
+			Help: "Number of server versions in the current allowlist snapshot by status.",
+		}, []string{"status"}),
+		loadTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mcp_registry_allowlist_load_timestamp_seconds",
+			Help: "Unix time the current allowlist snapshot was loaded.",
+		}),
+		reloadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mcp_registry_allowlist_reload_failures_total",
+			Help: "Total number of allowlist reloads rejected because of errors.",
+		}),
+		mirrored: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mcp_registry_upstream_mirrored_servers",
+			Help: "Number of valid server versions fetched from the upstream registry in the last sync.",
+		}),
+		syncTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mcp_registry_upstream_sync_timestamp_seconds",
+			Help: "Unix time of the last successful upstream registry sync.",
+		}),
+		syncFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mcp_registry_upstream_sync_failures_total",
+			Help: "Total number of failed upstream registry syncs.",
+		}),
+		remoteUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mcp_registry_remote_up",
+			Help: "Whether a remote answered the last probe, including with an authentication challenge, by server name and url.",
+		}, []string{"name", "url"}),
+		remoteLatency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mcp_registry_remote_probe_latency_seconds",
+			Help: "Latency of the last probe of a remote, by server name and url.",
+		}, []string{"name", "url"}),
+		remoteOAuth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mcp_registry_remote_oauth_metadata",
+			Help: "Whether a remote that requires authentication publishes OAuth protected resource metadata, by server name and url.",
+		}, []string{"name", "url"}),
+		probeTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mcp_registry_remote_probe_timestamp_seconds",
+			Help: "Unix time of the last round of remote probes.",
+		}),
+	}
+	m.expiredReviews = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "mcp_registry_expired_reviews",
+		Help: "Number of served high-risk server versions whose security review has expired.",
+	}, m.countExpiredReviews)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.notFound,
+		m.servers,
+		m.loadTimestamp,
+		m.reloadFailures,
+		m.mirrored,
+		m.syncTimestamp,
+		m.syncFailures,
+		m.remoteUp,
+		m.remoteLatency,
+		m.remoteOAuth,
+		m.probeTimestamp,
+		m.expiredReviews,
+	)
+
+	return m
+}
+
+// Handler returns the Prometheus exposition endpoint.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveSnapshot records the status breakdown of a newly loaded snapshot.
+func (m *Metrics) ObserveSnapshot(servers []ServerResponse, loadedAt time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.servers.Reset()
+	for _, status := range []string{StatusActive, StatusDeprecated, StatusDeleted} {
+		m.servers.WithLabelValues(status).Set(0)
+	}
+	for i := range servers {
+		m.servers.WithLabelValues(servers[i].Meta.Official.Status).Inc()
+	}
+	m.loadTimestamp.Set(float64(loadedAt.Unix()))
+	m.observeNames(servers, loadedAt)
+}
+
+// observeNames marks the names of servers as known at loadedAt, and forgets
+// the names that have not been served for knownNameRetention along with
+// their not found counts.
+func (m *Metrics) observeNames(servers []ServerResponse, loadedAt time.Time) {
+	m.knownNamesMu.Lock()
+	defer m.knownNamesMu.Unlock()
+
+	for i := range servers {
+		m.knownNames[servers[i].Server.Name] = loadedAt
+	}
+	for name, lastSeen := range m.knownNames {
+		if loadedAt.Sub(lastSeen) > knownNameRetention {
+			delete(m.knownNames, name)
+			m.notFound.DeleteLabelValues(name)
+		}
+	}
+}
+
+// ObserveReviews records when the reviews of the served high-risk servers
+// expire.
+func (m *Metrics) ObserveReviews(expiries []time.Time) {
+	if m == nil {
+		return
+	}
+	m.reviewExpiries.Store(&expiries)
+}
+
+func (m *Metrics) countExpiredReviews() float64 {
+	expiries := m.reviewExpiries.Load()
+	if expiries == nil {
+		return 0
+	}
+	now := time.Now()
+	expired := 0
+	for _, expiresAt := range *expiries {
+		if !now.Before(expiresAt) {
+			expired++
+		}
+	}
+	return float64(expired)
+}
+
+func (m *Metrics) ReloadFailed() {
+	if m == nil {
+		return
+	}
+	m.reloadFailures.Inc()
+}
+
+func (m *Metrics) UpstreamSynced(count int, syncedAt time.Time) {
+	if m == nil {
+		return
+	}
+	m.mirrored.Set(float64(count))
+	m.syncTimestamp.Set(float64(syncedAt.Unix()))
+}
+
+func (m *Metrics) UpstreamSyncFailed() {
+	if m == nil {
+		return
+	}
+	m.syncFailures.Inc()
+}
+
+// ServerNotFound counts a lookup for a server version that is not in the
+// registry. Names the registry has not served within knownNameRetention are
+// counted as unknown, since clients can ask for any name.
+func (m *Metrics) ServerNotFound(name string) {
+	if m == nil {
+		return
+	}
+
+	m.knownNamesMu.Lock()
+	defer m.knownNamesMu.Unlock()
+	if _, ok := m.knownNames[name]; !ok {
+		name = unknownServerName
+	}
+	m.notFound.WithLabelValues(name).Inc()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// metricsMiddleware counts and times every request under the given route
+// label, which should be the registered pattern rather than the raw path.
+func metricsMiddleware(m *Metrics, route string, next http.HandlerFunc) http.HandlerFunc {
+	if m == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(recorder, r)
+
+		method := methodLabel(r.Method)
+		status := strconv.Itoa(recorder.status)
+		m.requests.WithLabelValues(route, method, status).Inc()
+		m.requestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// ObserveHealth records the probe results served for the remotes of servers.
+// Remotes that are no longer served are dropped.
+func (m *Metrics) ObserveHealth(servers []ServerResponse) {
+	if m == nil {
+		return
+	}
+
+	m.remoteUp.Reset()
+	m.remoteLatency.Reset()
+	m.remoteOAuth.Reset()
+	for i := range servers {
+		if servers[i].Meta.Nav == nil {
+			continue
+		}
+		for _, health := range servers[i].Meta.Nav.Health {
+			labels := []string{servers[i].Server.Name, health.URL}
+			m.remoteUp.WithLabelValues(labels...).Set(boolValue(health.Status != HealthUnreachable))
+			m.remoteLatency.WithLabelValues(labels...).Set(float64(health.LatencyMs) / 1000)
+			m.remoteOAuth.WithLabelValues(labels...).Set(boolValue(health.OAuthMetadata))
+		}
+	}
+}
+
+func (m *Metrics) RemotesProbed(probedAt time.Time) {
+	if m == nil {
+		return
+	}
+	m.probeTimestamp.Set(float64(probedAt.Unix()))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
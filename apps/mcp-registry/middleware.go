@@ -3,6 +3,8 @@ package main
 import (
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -57,3 +59,54 @@ func loggingMiddleware(config *Config, next http.HandlerFunc) http.HandlerFunc {
 		}
 	}
 }
+
+// corsMiddleware applies the CORS policy of config to every response,
+// including errors, and answers preflight requests.
+func corsMiddleware(config *Config, next http.Handler) http.HandlerFunc {
+	anyOrigin := slices.Contains(config.CORSAllowedOrigins, "*")
+	methods := strings.Join(config.CORSAllowedMethods, ", ")
+	headers := strings.Join(config.CORSAllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.CORSMaxAge.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		switch {
+		case anyOrigin:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && slices.Contains(config.CORSAllowedOrigins, origin):
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		default:
+			w.Header().Add("Vary", "Origin")
+		}
+		if methods != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+		}
+		if headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+
+		if r.Method == http.MethodOptions {
+			if config.CORSMaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// apiContentSecurityPolicy keeps responses that are not HTML from being
+// rendered or framed. The catalog sets its own policy.
+const apiContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// securityHeadersMiddleware sets the security headers of every response.
+func securityHeadersMiddleware(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		w.Header().Set("Content-Security-Policy", apiContentSecurityPolicy)
+		next.ServeHTTP(w, r)
+	}
+}
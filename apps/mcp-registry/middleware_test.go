@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestObfuscateHeaders(t *testing.T) {
@@ -105,3 +106,119 @@ func TestLoggingMiddleware_DisabledEndpoint(t *testing.T) {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
 }
+
+func TestCORSMiddleware(t *testing.T) {
+	anyOrigin := &Config{
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: []string{"GET", "OPTIONS"},
+		CORSAllowedHeaders: []string{"Authorization", "Content-Type"},
+		CORSMaxAge:         10 * time.Minute,
+	}
+	listedOrigins := &Config{
+		CORSAllowedOrigins: []string{"https://portal.nav.no", "https://portal.intern.nav.no"},
+		CORSAllowedMethods: []string{"GET"},
+	}
+
+	tests := []struct {
+		name            string
+		config          *Config
+		method          string
+		origin          string
+		status          int
+		expectedStatus  int
+		expectedOrigin  string
+		expectedMethods string
+		expectedHeaders string
+		expectedMaxAge  string
+		expectedVary    string
+	}{
+		{"any origin", anyOrigin, http.MethodGet, "https://example.com", http.StatusOK, http.StatusOK, "*", "GET, OPTIONS", "Authorization, Content-Type", "", ""},
+		{"any origin error", anyOrigin, http.MethodGet, "https://example.com", http.StatusNotFound, http.StatusNotFound, "*", "GET, OPTIONS", "Authorization, Content-Type", "", ""},
+		{"any origin preflight", anyOrigin, http.MethodOptions, "https://example.com", http.StatusOK, http.StatusNoContent, "*", "GET, OPTIONS", "Authorization, Content-Type", "600", ""},
+		{"listed origin", listedOrigins, http.MethodGet, "https://portal.nav.no", http.StatusOK, http.StatusOK, "https://portal.nav.no", "GET", "", "", "Origin"},
+		{"listed origin error", listedOrigins, http.MethodGet, "https://portal.intern.nav.no", http.StatusInternalServerError, http.StatusInternalServerError, "https://portal.intern.nav.no", "GET", "", "", "Origin"},
+		{"unlisted origin", listedOrigins, http.MethodGet, "https://evil.example.com", http.StatusOK, http.StatusOK, "", "GET", "", "", "Origin"},
+		{"unlisted origin preflight", listedOrigins, http.MethodOptions, "https://evil.example.com", http.StatusOK, http.StatusNoContent, "", "GET", "", "", "Origin"},
+		{"no origin", listedOrigins, http.MethodGet, "", http.StatusOK, http.StatusOK, "", "GET", "", "", "Origin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := corsMiddleware(tt.config, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				called = true
+				w.WriteHeader(tt.status)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/v0.1/servers", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if called == (tt.method == http.MethodOptions) {
+				t.Errorf("expected only non-preflight requests to reach the handler, called=%v", called)
+			}
+
+			for header, expected := range map[string]string{
+				"Access-Control-Allow-Origin":  tt.expectedOrigin,
+				"Access-Control-Allow-Methods": tt.expectedMethods,
+				"Access-Control-Allow-Headers": tt.expectedHeaders,
+				"Access-Control-Max-Age":       tt.expectedMaxAge,
+				"Vary":                         tt.expectedVary,
+			} {
+				if got := w.Header().Get(header); got != expected {
+					t.Errorf("expected %s %q, got %q", header, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		expectedCSP string
+	}{
+		{
+			name:        "api response",
+			handler:     func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) },
+			expectedCSP: apiContentSecurityPolicy,
+		},
+		{
+			name:        "error response",
+			handler:     func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expectedCSP: apiContentSecurityPolicy,
+		},
+		{
+			name: "handler with its own policy",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Security-Policy", catalogContentSecurityPolicy("abc"))
+				w.WriteHeader(http.StatusOK)
+			},
+			expectedCSP: catalogContentSecurityPolicy("abc"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			securityHeadersMiddleware(tt.handler)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			for header, expected := range map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"Content-Security-Policy":   tt.expectedCSP,
+			} {
+				if got := w.Header().Get(header); got != expected {
+					t.Errorf("expected %s %q, got %q", header, expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+-- Server entries as written in the allowlist, with template variables in
+-- place. position keeps entries in the order they were added, so validation
+-- errors refer to the same server[i] across loads.
+CREATE TABLE servers (
+    name       text        NOT NULL,
+    version    text        NOT NULL,
+    entry      jsonb       NOT NULL,
+    position   bigint      GENERATED ALWAYS AS IDENTITY,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (name, version)
+);
@@ -0,0 +1,11 @@
+-- revision orders writes by when they were made. Writes hold the write
+-- lock, so a write that commits later always gets a higher revision, which
+-- is not true of updated_at when it is the start of the writing
+-- transaction. Replicas compare the highest revision to detect changes.
+CREATE SEQUENCE servers_revision;
+
+ALTER TABLE servers
+    ADD COLUMN revision bigint NOT NULL DEFAULT nextval('servers_revision'),
+    ALTER COLUMN updated_at SET DEFAULT clock_timestamp();
+
+ALTER SEQUENCE servers_revision OWNED BY servers.revision;
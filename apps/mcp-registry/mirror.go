@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	upstreamPageLimit = 100
+	// upstreamMaxPages bounds a single search, so a misbehaving upstream that
+	// keeps returning cursors cannot stall a sync forever.
+	upstreamMaxPages = 50
+	upstreamMaxBody  = 10 << 20
+)
+
+// Upstream fetches servers from an upstream MCP Registry v0.1 API, such as
+// the public registry at https://registry.modelcontextprotocol.io.
+type Upstream struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewUpstream(baseURL string, client *http.Client) *Upstream {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Upstream{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Fetch returns the latest upstream version of every server whose name
+// matches one of the include patterns.
+func (u *Upstream) Fetch(ctx context.Context, include []string) ([]StaticServerData, error) {
+	var servers []StaticServerData
+	seen := make(map[serverKey]bool)
+
+	for _, pattern := range include {
+		responses, err := u.list(ctx, patternSearch(pattern))
+		if err != nil {
+			return nil, err
+		}
+		for i := range responses {
+			s := &responses[i]
+			if !mirrorMatches(pattern, s.Server.Name) || seen[keyOf(s)] {
+				continue
+			}
+			seen[keyOf(s)] = true
+			servers = append(servers, staticServerFromUpstream(s))
+		}
+	}
+
+	return servers, nil
+}
+
+func (u *Upstream) list(ctx context.Context, search string) ([]ServerResponse, error) {
+	var servers []ServerResponse
+	cursor := ""
+
+	for range upstreamMaxPages {
+		query := url.Values{}
+		query.Set("version", VersionLatest)
+		query.Set("limit", strconv.Itoa(upstreamPageLimit))
+		if search != "" {
+			query.Set("search", search)
+		}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		page, err := u.get(ctx, u.baseURL+"/v0.1/servers?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, page.Servers...)
+
+		if page.Metadata.NextCursor == "" {
+			return servers, nil
+		}
+		cursor = page.Metadata.NextCursor
+	}
+
+	return nil, fmt.Errorf("upstream returned more than %d pages for search '%s'", upstreamMaxPages, search)
+}
+
+func (u *Upstream) get(ctx context.Context, rawURL string) (*ServerListResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upstream request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned %s for %s", resp.Status, rawURL)
+	}
+
+	var page ServerListResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, upstreamMaxBody)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("invalid upstream response from %s: %v", rawURL, err)
+	}
+	return &page, nil
+}
+
+// patternSearch returns the literal prefix of an include pattern, which is
+// used as the upstream search term to avoid listing the whole registry.
+func patternSearch(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+func mirrorMatches(pattern, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func (m *MirrorConfig) includes(name string) bool {
+	if m == nil {
+		return false
+	}
+	for _, pattern := range m.Include {
+		if mirrorMatches(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func staticServerFromUpstream(s *ServerResponse) StaticServerData {
+	server := StaticServerData{
+		Name:        s.Server.Name,
+		Description: s.Server.Description,
+		Version:     s.Server.Version,
+		Packages:    s.Server.Packages,
+		Remotes:     s.Server.Remotes,
+	}
+	if s.Meta.Official != nil {
+		server.Status = s.Meta.Official.Status
+		if !s.Meta.Official.PublishedAt.IsZero() {
+			server.PublishedAt = s.Meta.Official.PublishedAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return server
+}
+
+// validMirroredServers drops mirrored servers that would not pass
+// validation once mirror's overrides are applied, so a single bad upstream
+// entry does not block the others.
+func validMirroredServers(servers []StaticServerData, mirror *MirrorConfig) []StaticServerData {
+	valid := make([]StaticServerData, 0, len(servers))
+	existing := make(map[serverKey]bool)
+
+	for i := range servers {
+		server := mirror.apply(servers[i])
+		if err := validateServerEntry(&server, i, existing); err != nil {
+			slog.Warn("Skipping invalid upstream server", "name", servers[i].Name, "version", servers[i].Version, "error", err)
+			continue
+		}
+		existing[serverKey{servers[i].Name, servers[i].Version}] = true
+		valid = append(valid, servers[i])
+	}
+
+	return valid
+}
+
+// mergeMirrored adds the mirrored servers that local still includes to a copy
+// of local, applying local overrides. A local entry with the same name and
+// version wins over the mirrored one.
+func mergeMirrored(local *StaticRegistryData, mirrored []StaticServerData) *StaticRegistryData {
+	merged := &StaticRegistryData{
+		Servers: slices.Clone(local.Servers),
+		Mirror:  local.Mirror,
+	}
+	if local.Mirror == nil {
+		return merged
+	}
+
+	localKeys := make(map[serverKey]bool, len(local.Servers))
+	for i := range local.Servers {
+		localKeys[serverKey{local.Servers[i].Name, local.Servers[i].Version}] = true
+	}
+
+	for _, server := range mirrored {
+		if localKeys[serverKey{server.Name, server.Version}] || !local.Mirror.includes(server.Name) {
+			continue
+		}
+		merged.Servers = append(merged.Servers, local.Mirror.apply(server))
+	}
+
+	return merged
+}
+
+// apply returns server with the local override for its name, if any.
+func (m *MirrorConfig) apply(server StaticServerData) StaticServerData {
+	if m == nil {
+		return server
+	}
+	override, ok := m.Overrides[server.Name]
+	if !ok {
+		return server
+	}
+	if override.Status != "" {
+		server.Status = override.Status
+	}
+	if override.Description != "" {
+		server.Description = override.Description
+	}
+	if override.Remotes != nil {
+		server.Remotes = override.Remotes
+	}
+	return server
+}
+
+// validateMirror checks the mirror section of the allowlist.
+func validateMirror(mirror *MirrorConfig) error {
+	if mirror == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if len(mirror.Include) == 0 {
+		errs = append(errs, fmt.Errorf("mirror: 'include' must list at least one server name or pattern"))
+	}
+	for i, pattern := range mirror.Include {
+		if strings.TrimSpace(pattern) == "" {
+			errs = append(errs, fmt.Errorf("mirror.include[%d]: pattern cannot be empty", i))
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("mirror.include[%d]: invalid pattern '%s': %v", i, pattern, err))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(mirror.Overrides)) {
+		override := mirror.Overrides[name]
+		prefix := fmt.Sprintf("mirror.overrides['%s']", name)
+		switch override.Status {
+		case "", StatusActive, StatusDeprecated, StatusDeleted:
+		default:
+			errs = append(errs, fmt.Errorf("%s: 'status' must be one of: %s, %s, %s", prefix, StatusActive, StatusDeprecated, StatusDeleted))
+		}
+		if len(override.Description) > DescriptionMaxLength {
+			errs = append(errs, fmt.Errorf("%s: 'description' must be at most %d characters", prefix, DescriptionMaxLength))
+		}
+		for j := range override.Remotes {
+			if err := validateRemote(&override.Remotes[j], fmt.Sprintf("%s.remotes[%d]", prefix, j)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
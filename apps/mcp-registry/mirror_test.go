@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const upstreamTestAllowlist = `{
+  "servers": [
+    {"name": "com.atlassian/jira", "owner": "team-test", "description": "Jira", "version": "1.0.0", "publishedAt": "2025-01-01T00:00:00Z",
+     "remotes": [{"type": "streamable-http", "url": "https://jira.example.com/mcp"}]},
+    {"name": "com.atlassian/jira", "owner": "team-test", "description": "Jira", "version": "2.0.0", "publishedAt": "2025-02-01T00:00:00Z",
+     "remotes": [{"type": "streamable-http", "url": "https://jira.example.com/v2/mcp"}]},
+    {"name": "com.atlassian/confluence", "owner": "team-test", "description": "Confluence", "version": "1.0.0", "publishedAt": "2025-01-01T00:00:00Z",
+     "remotes": [{"type": "sse", "url": "https://confluence.example.com/sse"}]},
+    {"name": "io.github.github/github-mcp-server", "owner": "team-test", "description": "GitHub", "version": "0.5.0", "status": "deprecated",
+     "publishedAt": "2025-03-01T00:00:00Z", "remotes": [{"type": "streamable-http", "url": "https://api.githubcopilot.com/mcp/"}]},
+    {"name": "io.github.other/unrelated", "owner": "team-test", "description": "Not selected", "version": "1.0.0", "publishedAt": "2025-01-01T00:00:00Z"}
+  ]
+}`
+
+// newTestUpstream serves upstreamTestAllowlist through the registry's own
+// list handler, which implements the same v0.1 API as the public registry.
+func newTestUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := loadTestRegistry(t, upstreamTestAllowlist, time.Now())
+	server := httptest.NewServer(makeServersListHandler(upstream))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mirrorTestRegistry(t *testing.T, upstreamURL, content string) (*Registry, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, content, time.Now().Add(-time.Hour))
+
+	config := testConfig()
+	config.UpstreamURL = upstreamURL
+	registry := NewRegistry(newFileStore(path, config), config, nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+	return registry, path
+}
+
+func TestUpstream_Fetch(t *testing.T) {
+	server := newTestUpstream(t)
+	upstream := NewUpstream(server.URL+"/", nil)
+
+	servers, err := upstream.Fetch(context.Background(), []string{"com.atlassian/*", "io.github.github/github-mcp-server", "com.atlassian/jira"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := make(map[serverKey]StaticServerData)
+	for _, s := range servers {
+		found[serverKey{s.Name, s.Version}] = s
+	}
+	if len(servers) != len(found) {
+		t.Errorf("expected no duplicates, got %d servers for %d keys", len(servers), len(found))
+	}
+
+	expected := []serverKey{
+		{"com.atlassian/jira", "2.0.0"},
+		{"com.atlassian/confluence", "1.0.0"},
+		{"io.github.github/github-mcp-server", "0.5.0"},
+	}
+	if len(found) != len(expected) {
+		t.Errorf("expected %d servers, got %v", len(expected), servers)
+	}
+	for _, key := range expected {
+		if _, ok := found[key]; !ok {
+			t.Errorf("expected %s@%s to be mirrored", key.name, key.version)
+		}
+	}
+
+	github := found[serverKey{"io.github.github/github-mcp-server", "0.5.0"}]
+	if github.Status != StatusDeprecated {
+		t.Errorf("expected upstream status to be kept, got %q", github.Status)
+	}
+	if github.PublishedAt != "2025-03-01T00:00:00Z" {
+		t.Errorf("expected upstream publishedAt to be kept, got %q", github.PublishedAt)
+	}
+	if len(github.Remotes) != 1 || github.Remotes[0].URL != "https://api.githubcopilot.com/mcp/" {
+		t.Errorf("expected upstream remotes to be kept, got %v", github.Remotes)
+	}
+}
+
+func TestUpstream_FetchPaginates(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+
+		page := ServerListResponse{Servers: []ServerResponse{{Server: ServerJSON{Name: "io.github.test/first", Version: "1.0.0"}}}}
+		page.Metadata.NextCursor = "next"
+		if r.URL.Query().Get("cursor") == "next" {
+			page = ServerListResponse{Servers: []ServerResponse{{Server: ServerJSON{Name: "io.github.test/second", Version: "1.0.0"}}}}
+		}
+		respondJSON(w, http.StatusOK, page)
+	}))
+	defer server.Close()
+
+	servers, err := NewUpstream(server.URL, nil).Fetch(context.Background(), []string{"io.github.test/*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(servers) != 2 {
+		t.Errorf("expected servers from both pages, got %v", servers)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 upstream requests, got %v", queries)
+	}
+	for _, expected := range []string{"version=latest", "limit=100", "search=io.github.test%2F"} {
+		if !strings.Contains(queries[0], expected) {
+			t.Errorf("expected upstream query %q to contain %q", queries[0], expected)
+		}
+	}
+}
+
+func TestUpstream_FetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := NewUpstream(server.URL, nil).Fetch(context.Background(), []string{"io.github.test/*"})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected error with upstream status, got %v", err)
+	}
+}
+
+func TestRegistry_Sync(t *testing.T) {
+	server := newTestUpstream(t)
+	registry, _ := mirrorTestRegistry(t, server.URL, `{
+  "servers": [
+    {"name": "com.atlassian/confluence", "owner": "team-test", "description": "Nav's Confluence", "version": "1.0.0",
+     "remotes": [{"type": "streamable-http", "url": "https://confluence.{{domain_internal}}/mcp"}]}
+  ],
+  "mirror": {
+    "include": ["com.atlassian/*", "io.github.github/github-mcp-server"],
+    "overrides": {
+      "io.github.github/github-mcp-server": {
+        "status": "active",
+        "description": "GitHub via Nav proxy",
+        "remotes": [{"type": "streamable-http", "url": "https://github-mcp.{{domain_internal}}/mcp"}]
+      }
+    }
+  }
+}`)
+
+	if servers := registry.Servers(); len(servers) != 1 {
+		t.Fatalf("expected only the local server before the first sync, got %d", len(servers))
+	}
+
+	if err := registry.Sync(context.Background()); err != nil {
+		t.Fatalf("unexpected sync error: %v", err)
+	}
+
+	if servers := registry.Servers(); len(servers) != 3 {
+		t.Errorf("expected 3 servers after sync, got %d", len(servers))
+	}
+
+	confluence, ok := registry.Find("com.atlassian/confluence", "1.0.0")
+	if !ok || confluence.Server.Description != "Nav's Confluence" {
+		t.Errorf("expected local entry to win over upstream, got %+v", confluence.Server)
+	}
+
+	jira, ok := registry.Find("com.atlassian/jira", VersionLatest)
+	if !ok || jira.Server.Version != "2.0.0" {
+		t.Errorf("expected mirrored jira 2.0.0, got %+v (found %v)", jira.Server, ok)
+	}
+
+	github, ok := registry.Find("io.github.github/github-mcp-server", "0.5.0")
+	if !ok {
+		t.Fatal("expected mirrored github server")
+	}
+	if github.Server.Description != "GitHub via Nav proxy" {
+		t.Errorf("expected description override, got %q", github.Server.Description)
+	}
+	if github.Meta.Official.Status != StatusActive || !github.Meta.Official.IsLatest {
+		t.Errorf("expected status override to make it the active latest version, got %+v", github.Meta.Official)
+	}
+	if len(github.Server.Remotes) != 1 || github.Server.Remotes[0].URL != "https://github-mcp.intern.dev.nav.no/mcp" {
+		t.Errorf("expected remotes override with substituted variables, got %v", github.Server.Remotes)
+	}
+
+	if _, ok := registry.Find("io.github.other/unrelated", "1.0.0"); ok {
+		t.Error("expected servers not matched by include to stay out")
+	}
+}
+
+func TestRegistry_SyncKeepsLastMirroredOnFailure(t *testing.T) {
+	var failing bool
+	var mu sync.Mutex
+	upstream := loadTestRegistry(t, upstreamTestAllowlist, time.Now())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		serversListHandler(w, r, upstream)
+	}))
+	defer server.Close()
+
+	registry, path := mirrorTestRegistry(t, server.URL, `{
+  "servers": [{"name": "io.github.navikt/local", "owner": "team-test", "description": "Local", "version": "1.0.0"}],
+  "mirror": {"include": ["com.atlassian/jira"]}
+}`)
+
+	if err := registry.Sync(context.Background()); err != nil {
+		t.Fatalf("unexpected sync error: %v", err)
+	}
+	if _, ok := registry.Find("com.atlassian/jira", "2.0.0"); !ok {
+		t.Fatal("expected jira to be mirrored")
+	}
+
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+
+	if err := registry.Sync(context.Background()); err == nil {
+		t.Error("expected sync error when upstream fails")
+	}
+	if _, ok := registry.Find("com.atlassian/jira", "2.0.0"); !ok {
+		t.Error("expected last mirrored servers to keep serving after a failed sync")
+	}
+
+	writeAllowlist(t, path, `{
+  "servers": [{"name": "io.github.navikt/local", "owner": "team-test", "description": "Local v2", "version": "1.0.0"}],
+  "mirror": {"include": ["com.atlassian/jira"]}
+}`, time.Now())
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to reload allowlist: %v", err)
+	}
+	if _, ok := registry.Find("com.atlassian/jira", "2.0.0"); !ok {
+		t.Error("expected mirrored servers to survive a local reload")
+	}
+
+	writeAllowlist(t, path, `{"servers": [{"name": "io.github.navikt/local", "owner": "team-test", "description": "Local", "version": "1.0.0"}]}`, time.Now().Add(time.Minute))
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to reload allowlist: %v", err)
+	}
+	if _, ok := registry.Find("com.atlassian/jira", "2.0.0"); ok {
+		t.Error("expected mirrored servers to be dropped once no longer included")
+	}
+}
+
+func TestRegistry_MirrorWithoutUpstream(t *testing.T) {
+	registry := loadTestRegistry(t, registryTestAllowlist, time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		registry.Mirror(context.Background(), time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("expected Mirror to return immediately without an upstream")
+	}
+}
+
+func TestValidMirroredServers(t *testing.T) {
+	servers := []StaticServerData{
+		{Name: "io.github.test/valid", Description: "Valid", Version: "1.0.0"},
+		{Name: "not a name", Description: "Invalid", Version: "1.0.0"},
+		{Name: "io.github.test/valid", Description: "Duplicate", Version: "1.0.0"},
+		{Name: "io.github.test/too-long", Description: strings.Repeat("x", DescriptionMaxLength+1), Version: "1.0.0"},
+	}
+
+	valid := validMirroredServers(servers, nil)
+
+	if len(valid) != 1 || valid[0].Description != "Valid" {
+		t.Errorf("expected only the first server to be kept, got %v", valid)
+	}
+
+	mirror := &MirrorConfig{
+		Include:   []string{"io.github.test/*"},
+		Overrides: map[string]MirrorOverride{"io.github.test/too-long": {Description: "Shortened"}},
+	}
+	valid = validMirroredServers(servers, mirror)
+
+	if len(valid) != 2 || valid[1].Name != "io.github.test/too-long" {
+		t.Errorf("expected the override to make the long description valid, got %v", valid)
+	}
+	if valid[1].Description == "Shortened" {
+		t.Error("expected the upstream entry to be kept as fetched, with overrides applied at merge")
+	}
+}
+
+func TestValidateMirror(t *testing.T) {
+	tests := []struct {
+		name          string
+		mirror        *MirrorConfig
+		expectedError string
+	}{
+		{"no mirror", nil, ""},
+		{"valid", &MirrorConfig{
+			Include: []string{"com.atlassian/*", "io.github.github/github-mcp-server"},
+			Overrides: map[string]MirrorOverride{
+				"com.atlassian/jira": {Status: StatusDeprecated, Remotes: []Transport{{Type: TransportTypeSSE, URL: "https://jira.example.com/sse"}}},
+			},
+		}, ""},
+		{"empty include", &MirrorConfig{}, "mirror: 'include' must list at least one"},
+		{"empty pattern", &MirrorConfig{Include: []string{" "}}, "mirror.include[0]: pattern cannot be empty"},
+		{"invalid pattern", &MirrorConfig{Include: []string{"com.atlassian/[jira"}}, "mirror.include[0]: invalid pattern"},
+		{"invalid status", &MirrorConfig{
+			Include:   []string{"com.atlassian/*"},
+			Overrides: map[string]MirrorOverride{"com.atlassian/jira": {Status: "retired"}},
+		}, "mirror.overrides['com.atlassian/jira']: 'status' must be one of"},
+		{"long description", &MirrorConfig{
+			Include:   []string{"com.atlassian/*"},
+			Overrides: map[string]MirrorOverride{"com.atlassian/jira": {Description: strings.Repeat("x", DescriptionMaxLength+1)}},
+		}, "'description' must be at most"},
+		{"invalid remote", &MirrorConfig{
+			Include:   []string{"com.atlassian/*"},
+			Overrides: map[string]MirrorOverride{"com.atlassian/jira": {Remotes: []Transport{{Type: TransportTypeStdio}}}},
+		}, "mirror.overrides['com.atlassian/jira'].remotes[0]: 'type' must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMirror(tt.mirror)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRegistryWarnings_UnmatchedOverride(t *testing.T) {
+	warnings := registryWarnings(&StaticRegistryData{
+		Servers: []StaticServerData{{Name: "io.github.test/server", PublishedAt: "2025-01-01T00:00:00Z", Owner: "team-test"}},
+		Mirror: &MirrorConfig{
+			Include:   []string{"com.atlassian/*"},
+			Overrides: map[string]MirrorOverride{"io.github.github/github-mcp-server": {Status: StatusDeprecated}},
+		},
+	})
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "is not matched by any 'include' pattern") {
+		t.Errorf("expected unmatched override warning, got %v", warnings)
+	}
+}
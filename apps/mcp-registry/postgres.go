@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// postgresLockID is the advisory lock that serializes migrations and writes
+// across replicas, so every write is validated against the latest entries.
+const postgresLockID int64 = 4_711_001
+
+// postgresStore keeps entries in PostgreSQL, so every replica serves the
+// same state. Changes made by one replica are picked up by the others the
+// next time they poll for changes.
+type postgresStore struct {
+	pool   *pgxpool.Pool
+	config *Config
+
+	mu       sync.Mutex
+	revision postgresRevision
+}
+
+// postgresRevision identifies the state of the servers table by its highest
+// revision, which every write increases. Entries are never deleted by the
+// registry, but counting them also catches manual deletes. updatedAt is
+// when the last entry was written.
+type postgresRevision struct {
+	count     int
+	revision  int64
+	updatedAt time.Time
+}
+
+// pgQuerier is implemented by both the pool and a transaction.
+type pgQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// newPostgresStore connects to the database and applies any pending
+// migrations.
+func newPostgresStore(ctx context.Context, databaseURL string, config *Config) (*postgresStore, error) {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("cannot connect to database: %v", err)
+	}
+
+	s := &postgresStore{pool: pool, config: config}
+	if err := s.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *postgresStore) Close() {
+	s.pool.Close()
+}
+
+func (s *postgresStore) String() string {
+	return "postgres"
+}
+
+// migrate applies the embedded migrations that have not been applied yet,
+// in file name order, each in its own transaction.
+func (s *postgresStore) migrate(ctx context.Context) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", postgresLockID); err != nil {
+		return fmt.Errorf("cannot lock for migrations: %v", err)
+	}
+	defer func() { _, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockID) }()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    text        PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("cannot create schema_migrations: %v", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("cannot read applied migrations: %v", err)
+	}
+	applied, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("cannot read applied migrations: %v", err)
+	}
+
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		if slices.Contains(applied, version) {
+			continue
+		}
+
+		migration, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(migration)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %v", version, err)
+		}
+		slog.Info("Applied database migration", "version", version)
+	}
+
+	return nil
+}
+
+// Import copies the servers of the allowlist file at path into an empty
+// store, so a new database starts out with the current allowlist. It
+// returns the number of servers imported, which is zero if the store
+// already has entries. The store does not keep a mirror section, so a file
+// with one is refused rather than served without its mirrored servers.
+func (s *postgresStore) Import(ctx context.Context, path string) (int, error) {
+	allowlist, err := newFileStore(path, s.config).read()
+	if err != nil {
+		return 0, err
+	}
+	if len(allowlist.Mirror) > 0 {
+		return 0, errors.New("the mirror section requires the allowlist file store, remove it or unset DATABASE_URL")
+	}
+
+	imported := 0
+	err = s.update(ctx, func(tx pgx.Tx) error {
+		var count int
+		if err := tx.QueryRow(ctx, "SELECT count(*) FROM servers").Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		for i, entry := range allowlist.Servers {
+			var server StaticServerData
+			if err := json.Unmarshal(entry, &server); err != nil {
+				return fmt.Errorf("server[%d]: %v", i, err)
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO servers (name, version, entry) VALUES ($1, $2, $3)",
+				server.Name, server.Version, string(entry)); err != nil {
+				return fmt.Errorf("server[%d]: %v", i, err)
+			}
+			imported++
+		}
+		return nil
+	})
+	return imported, err
+}
+
+func (s *postgresStore) List(ctx context.Context) (*StaticRegistryData, time.Time, error) {
+	entries, revision, err := loadEntries(ctx, s.pool)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	s.mu.Lock()
+	s.revision = revision
+	s.mu.Unlock()
+
+	document, err := json.Marshal(rawAllowlist{Servers: entries})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := reportResult(checkAllowList(document, s.config), s.String())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, revision.updatedAt.UTC().Truncate(time.Second), nil
+}
+
+func (s *postgresStore) Changed(ctx context.Context) (bool, error) {
+	var revision postgresRevision
+	err := s.pool.QueryRow(ctx, "SELECT count(*), coalesce(max(revision), 0) FROM servers").
+		Scan(&revision.count, &revision.revision)
+	if err != nil {
+		return false, fmt.Errorf("cannot read servers: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return revision.count != s.revision.count || revision.revision != s.revision.revision, nil
+}
+
+func (s *postgresStore) GetVersion(ctx context.Context, name, version string) (StaticServerData, error) {
+	return getEntry(ctx, s.pool, name, version, false)
+}
+
+func (s *postgresStore) Put(ctx context.Context, server StaticServerData) error {
+	entry, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `INSERT INTO servers (name, version, entry) VALUES ($1, $2, $3)
+			ON CONFLICT (name, version) DO NOTHING`, server.Name, server.Version, string(entry))
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return errServerExists
+		}
+		return nil
+	})
+}
+
+func (s *postgresStore) SetStatus(ctx context.Context, name, version string, change statusChange) error {
+	return s.update(ctx, func(tx pgx.Tx) error {
+		server, err := getEntry(ctx, tx, name, version, true)
+		if err != nil {
+			return err
+		}
+		change.apply(&server)
+		entry, err := json.Marshal(server)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE servers SET entry = $3, revision = nextval('servers_revision'), updated_at = clock_timestamp()
+			WHERE name = $1 AND version = $2`, name, version, string(entry))
+		return err
+	})
+}
+
+// update runs change in a transaction that holds the write lock, and only
+// commits if every entry is still valid afterwards.
+func (s *postgresStore) update(ctx context.Context, change func(pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", postgresLockID); err != nil {
+			return fmt.Errorf("cannot lock servers: %v", err)
+		}
+
+		if err := change(tx); err != nil {
+			return err
+		}
+
+		entries, _, err := loadEntries(ctx, tx)
+		if err != nil {
+			return err
+		}
+		document, err := json.Marshal(rawAllowlist{Servers: entries})
+		if err != nil {
+			return err
+		}
+		if report := checkAllowList(document, s.config); len(report.errors) > 0 {
+			return fmt.Errorf("%w: %w", errInvalidAllowlist, errors.Join(report.errors...))
+		}
+		return nil
+	})
+}
+
+func loadEntries(ctx context.Context, q pgQuerier) ([]json.RawMessage, postgresRevision, error) {
+	rows, err := q.Query(ctx, "SELECT entry, revision, updated_at FROM servers ORDER BY position")
+	if err != nil {
+		return nil, postgresRevision{}, fmt.Errorf("cannot read servers: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []json.RawMessage
+	revision := postgresRevision{updatedAt: time.Unix(0, 0)}
+	for rows.Next() {
+		var entry []byte
+		var entryRevision int64
+		var updatedAt time.Time
+		if err := rows.Scan(&entry, &entryRevision, &updatedAt); err != nil {
+			return nil, postgresRevision{}, fmt.Errorf("cannot read servers: %v", err)
+		}
+		entries = append(entries, entry)
+		revision.revision = max(revision.revision, entryRevision)
+		if updatedAt.After(revision.updatedAt) {
+			revision.updatedAt = updatedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, postgresRevision{}, fmt.Errorf("cannot read servers: %v", err)
+	}
+
+	revision.count = len(entries)
+	return entries, revision, nil
+}
+
+func getEntry(ctx context.Context, q pgQuerier, name, version string, forUpdate bool) (StaticServerData, error) {
+	query := "SELECT entry FROM servers WHERE name = $1 AND version = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var entry []byte
+	if err := q.QueryRow(ctx, query, name, version).Scan(&entry); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return StaticServerData{}, errServerNotFound
+		}
+		return StaticServerData{}, fmt.Errorf("cannot read server: %v", err)
+	}
+
+	var server StaticServerData
+	if err := json.Unmarshal(entry, &server); err != nil {
+		return StaticServerData{}, fmt.Errorf("invalid stored server: %v", err)
+	}
+	return server, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/navikt/copilot/libs/problem"
+)
+
+// problemTypeBase prefixes the URIs of the problem types of the registry.
+// They identify the type and are not meant to be fetched.
+const problemTypeBase = "https://mcp-registry.nav.no/problems/"
+
+var (
+	problemInvalidQuery    = problem.Type{URI: problemTypeBase + "invalid-query", Title: "Invalid query parameter", Status: http.StatusBadRequest}
+	problemInvalidPath     = problem.Type{URI: problemTypeBase + "invalid-path", Title: "Invalid path", Status: http.StatusBadRequest}
+	problemInvalidRequest  = problem.Type{URI: problemTypeBase + "invalid-request", Title: "Invalid request body", Status: http.StatusBadRequest}
+	problemInvalidServer   = problem.Type{URI: problemTypeBase + "invalid-server", Title: "Invalid server", Status: http.StatusBadRequest}
+	problemServerNotFound  = problem.Type{URI: problemTypeBase + "server-not-found", Title: "Server not found", Status: http.StatusNotFound}
+	problemServerDeleted   = problem.Type{URI: problemTypeBase + "server-deleted", Title: "Server deleted", Status: http.StatusGone}
+	problemServerExists    = problem.Type{URI: problemTypeBase + "server-exists", Title: "Server version already exists", Status: http.StatusConflict}
+	problemNoClientConfig  = problem.Type{URI: problemTypeBase + "no-client-config", Title: "No client configuration", Status: http.StatusNotFound}
+	problemSignedAllowlist = problem.Type{URI: problemTypeBase + "signed-allowlist", Title: "Allowlist is signed", Status: http.StatusConflict}
+	problemNotSigned       = problem.Type{URI: problemTypeBase + "not-signed", Title: "Allowlist is not signed", Status: http.StatusNotFound}
+	problemUnauthorized    = problem.Status(http.StatusUnauthorized)
+	problemForbidden       = problem.Status(http.StatusForbidden)
+	problemInternalError   = problem.Status(http.StatusInternalServerError)
+	problemNotReady        = problem.Status(http.StatusServiceUnavailable)
+)
+
+// serverProblem returns an occurrence of t about a server, and a version of
+// it if version is not empty.
+func serverProblem(t problem.Type, detail, name, version string) *problem.Details {
+	p := t.New(detail).With("name", name)
+	if version != "" {
+		p.With("version", version)
+	}
+	return p
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 30
+	MaxPageLimit     = 100
+)
+
+// listQuery holds the MCP Registry v0.1 query parameters for listing servers.
+type listQuery struct {
+	cursor       *serverKey
+	limit        int
+	search       string
+	updatedSince time.Time
+	version      string
+	// includeDeleted lists deleted versions too, which are hidden by default.
+	includeDeleted bool
+	// risks lists only servers reviewed with one of these risk levels.
+	risks []string
+}
+
+type cursorData struct {
+	Name    string `json:"n"`
+	Version string `json:"v"`
+}
+
+func parseListQuery(values url.Values) (listQuery, error) {
+	query := listQuery{limit: DefaultPageLimit}
+
+	if raw := values.Get("cursor"); raw != "" {
+		key, err := decodeCursor(raw)
+		if err != nil {
+			return listQuery{}, err
+		}
+		query.cursor = &key
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return listQuery{}, fmt.Errorf("invalid limit '%s': must be an integer", raw)
+		}
+		if limit < 1 || limit > MaxPageLimit {
+			return listQuery{}, fmt.Errorf("invalid limit %d: must be between 1 and %d", limit, MaxPageLimit)
+		}
+		query.limit = limit
+	}
+
+	query.search = strings.ToLower(strings.TrimSpace(values.Get("search")))
+
+	if raw := values.Get("updated_since"); raw != "" {
+		updatedSince, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return listQuery{}, fmt.Errorf("invalid updated_since '%s': must be RFC3339", raw)
+		}
+		query.updatedSince = updatedSince
+	}
+
+	query.version = values.Get("version")
+
+	includeDeleted, err := parseIncludeDeleted(values)
+	if err != nil {
+		return listQuery{}, err
+	}
+	query.includeDeleted = includeDeleted
+
+	if raw := values.Get("risk"); raw != "" {
+		for risk := range strings.SplitSeq(raw, ",") {
+			risk = strings.TrimSpace(risk)
+			if !slices.Contains(knownRisks, risk) {
+				return listQuery{}, fmt.Errorf("invalid risk '%s': must be one of %s", risk, strings.Join(knownRisks, ", "))
+			}
+			query.risks = append(query.risks, risk)
+		}
+	}
+
+	return query, nil
+}
+
+// apply filters servers, which must be in listing order, and returns one page
+// together with the cursor for the next page, if any.
+func (q listQuery) apply(servers []ServerResponse) ([]ServerResponse, string) {
+	page := make([]ServerResponse, 0, min(q.limit, len(servers)))
+
+	for i := range servers {
+		s := &servers[i]
+		if q.cursor != nil && compareServerKeys(keyOf(s), *q.cursor) <= 0 {
+			continue
+		}
+		if !q.matches(s) {
+			continue
+		}
+		if len(page) == q.limit {
+			return page, encodeCursor(keyOf(&page[len(page)-1]))
+		}
+		page = append(page, *s)
+	}
+
+	return page, ""
+}
+
+// parseIncludeDeleted reads the include_deleted parameter, which defaults to
+// false.
+func parseIncludeDeleted(values url.Values) (bool, error) {
+	raw := values.Get("include_deleted")
+	if raw == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid include_deleted '%s': must be true or false", raw)
+	}
+	return includeDeleted, nil
+}
+
+// parseClient returns the client to generate configuration for, which
+// defaults to VS Code.
+func parseClient(values url.Values) (string, error) {
+	client := values.Get("client")
+	if client == "" {
+		return ClientVSCode, nil
+	}
+	if !slices.Contains(knownClients, client) {
+		return "", fmt.Errorf("invalid client '%s': must be one of %s", client, strings.Join(knownClients, ", "))
+	}
+	return client, nil
+}
+
+func (q listQuery) matches(s *ServerResponse) bool {
+	if !q.includeDeleted && s.Meta.Official.Status == StatusDeleted {
+		return false
+	}
+
+	if q.search != "" &&
+		!strings.Contains(strings.ToLower(s.Server.Name), q.search) &&
+		!strings.Contains(strings.ToLower(s.Server.Description), q.search) {
+		return false
+	}
+
+	if !q.updatedSince.IsZero() && s.Meta.Official.UpdatedAt.Before(q.updatedSince) {
+		return false
+	}
+
+	if len(q.risks) > 0 && (s.Meta.Nav == nil || s.Meta.Nav.Review == nil || !slices.Contains(q.risks, s.Meta.Nav.Review.Risk)) {
+		return false
+	}
+
+	switch q.version {
+	case "":
+	case VersionLatest:
+		if !s.Meta.Official.IsLatest {
+			return false
+		}
+	default:
+		if s.Server.Version != q.version {
+			return false
+		}
+	}
+
+	return true
+}
+
+// encodeCursor returns an opaque cursor pointing just past key. Cursors refer
+// to a position in the listing order rather than an index, so they stay valid
+// when servers are added or removed between requests.
+func encodeCursor(key serverKey) string {
+	data, err := json.Marshal(cursorData{Name: key.name, Version: key.version})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (serverKey, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return serverKey{}, fmt.Errorf("invalid cursor")
+	}
+
+	var c cursorData
+	if err := json.Unmarshal(data, &c); err != nil || c.Name == "" {
+		return serverKey{}, fmt.Errorf("invalid cursor")
+	}
+
+	return serverKey{name: c.Name, version: c.Version}, nil
+}
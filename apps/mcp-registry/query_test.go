@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const queryTestAllowlist = `{"servers": [
+	{"name": "io.github.test/alpha", "owner": "team-test", "description": "Alpha tools for Kotlin", "version": "1.0.0"},
+	{"name": "io.github.test/alpha", "owner": "team-test", "description": "Alpha tools for Kotlin", "version": "2.0.0"},
+	{"name": "io.github.test/beta", "owner": "team-test", "description": "Beta database helper", "version": "1.0.0",
+		"review": {"risk": "high", "reviewedAt": "2025-01-01", "reviewer": "appsec"}},
+	{"name": "io.github.test/gamma", "owner": "team-test", "description": "Gamma observability", "version": "0.1.0",
+		"review": {"risk": "low"}},
+	{"name": "io.github.test/delta", "owner": "team-test", "description": "Delta KOTLIN linter", "version": "3.1.0"},
+	{"name": "io.github.test/delta", "description": "Delta KOTLIN linter", "version": "3.2.0", "status": "deleted"}
+]}`
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expectError string
+	}{
+		{"empty", "", ""},
+		{"valid limit", "limit=10", ""},
+		{"max limit", "limit=100", ""},
+		{"limit zero", "limit=0", "must be between 1 and 100"},
+		{"limit too large", "limit=101", "must be between 1 and 100"},
+		{"limit not a number", "limit=ten", "must be an integer"},
+		{"valid updated_since", "updated_since=2025-01-01T00:00:00Z", ""},
+		{"invalid updated_since", "updated_since=2025-01-01", "must be RFC3339"},
+		{"malformed cursor", "cursor=@@@", "invalid cursor"},
+		{"cursor not json", "cursor=" + "bm90LWpzb24", "invalid cursor"},
+		{"valid cursor", "cursor=" + encodeCursor(serverKey{"io.github.test/alpha", "1.0.0"}), ""},
+		{"version latest", "version=latest", ""},
+		{"include deleted", "include_deleted=true", ""},
+		{"invalid include_deleted", "include_deleted=yes", "must be true or false"},
+		{"risk", "risk=high", ""},
+		{"several risks", "risk=medium,high", ""},
+		{"unknown risk", "risk=critical", "must be one of low, medium, high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			_, err := parseListQuery(values)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing '%s', got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := serverKey{name: "io.github.test/server", version: "1.0.0-rc.1"}
+
+	decoded, err := decodeCursor(encodeCursor(key))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if decoded != key {
+		t.Errorf("expected %+v, got %+v", key, decoded)
+	}
+}
+
+func TestListQuery_Pagination(t *testing.T) {
+	registry := loadTestRegistry(t, queryTestAllowlist, time.Now())
+
+	var seen []string
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		values := url.Values{"limit": {"2"}}
+		if cursor != "" {
+			values.Set("cursor", cursor)
+		}
+		query, err := parseListQuery(values)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		servers, next := query.apply(registry.Servers())
+		if len(servers) > 2 {
+			t.Fatalf("expected at most 2 servers per page, got %d", len(servers))
+		}
+		for _, s := range servers {
+			seen = append(seen, s.Server.Name+"@"+s.Server.Version)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []string{
+		"io.github.test/alpha@2.0.0",
+		"io.github.test/alpha@1.0.0",
+		"io.github.test/beta@1.0.0",
+		"io.github.test/delta@3.1.0",
+		"io.github.test/gamma@0.1.0",
+	}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, seen)
+	}
+}
+
+func TestListQuery_CursorStableAcrossChanges(t *testing.T) {
+	registry := loadTestRegistry(t, queryTestAllowlist, time.Now())
+
+	query, _ := parseListQuery(url.Values{"limit": {"2"}})
+	_, next := query.apply(registry.Servers())
+
+	// A server inserted before the cursor position must not shift the next page.
+	changed := loadTestRegistry(t, strings.Replace(queryTestAllowlist, `"servers": [`,
+		`"servers": [{"name": "io.github.test/aardvark", "owner": "team-test", "description": "New", "version": "1.0.0"},`, 1), time.Now())
+
+	query, _ = parseListQuery(url.Values{"limit": {"2"}, "cursor": {next}})
+	servers, _ := query.apply(changed.Servers())
+
+	if len(servers) == 0 || servers[0].Server.Name != "io.github.test/beta" {
+		t.Errorf("expected next page to start at beta, got %+v", servers)
+	}
+}
+
+func TestListQuery_Filters(t *testing.T) {
+	modTime := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	registry := loadTestRegistry(t, queryTestAllowlist, modTime)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"no filters", "", 5},
+		{"search by description is case-insensitive", "search=kotlin", 3},
+		{"search by name", "search=beta", 1},
+		{"search without match", "search=nothing", 0},
+		{"updated since before load", "updated_since=2025-01-01T00:00:00Z", 5},
+		{"updated since after load", "updated_since=2025-07-01T00:00:00Z", 0},
+		{"latest only", "version=latest", 4},
+		{"exact version", "version=1.0.0", 2},
+		{"search and latest", "search=alpha&version=latest", 1},
+		{"deleted hidden by exact version", "version=3.2.0", 0},
+		{"include deleted", "include_deleted=true", 6},
+		{"include deleted and exact version", "include_deleted=true&version=3.2.0", 1},
+		{"high risk", "risk=high", 1},
+		{"several risks", "risk=low,high", 2},
+		{"risk leaves out unreviewed servers", "risk=medium", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			query, err := parseListQuery(values)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			servers, next := query.apply(registry.Servers())
+			if len(servers) != tt.expected {
+				t.Errorf("expected %d servers, got %d", tt.expected, len(servers))
+			}
+			if next != "" {
+				t.Errorf("expected no next cursor, got %s", next)
+			}
+		})
+	}
+}
+
+func TestServersListHandler_Pagination(t *testing.T) {
+	registry := loadTestRegistry(t, queryTestAllowlist, time.Now())
+
+	req := httptest.NewRequest(http.MethodGet, "/v0.1/servers?limit=3", nil)
+	w := httptest.NewRecorder()
+
+	serversListHandler(w, req, registry)
+
+	resp := w.Result()
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var response ServerListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.Metadata.Count != 3 {
+		t.Errorf("expected count 3, got %d", response.Metadata.Count)
+	}
+
+	if response.Metadata.NextCursor == "" {
+		t.Error("expected nextCursor to be set")
+	}
+}
+
+func TestServersListHandler_InvalidQuery(t *testing.T) {
+	registry := loadTestRegistry(t, queryTestAllowlist, time.Now())
+
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=garbage!", "updated_since=yesterday"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0.1/servers?"+query, nil)
+			w := httptest.NewRecorder()
+
+			serversListHandler(w, req, registry)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestParseClient(t *testing.T) {
+	tests := []struct {
+		query       string
+		expected    string
+		expectError bool
+	}{
+		{"", ClientVSCode, false},
+		{"client=vscode", ClientVSCode, false},
+		{"client=copilot-cli", ClientCopilotCLI, false},
+		{"client=generic", ClientGeneric, false},
+		{"client=VSCode", "", true},
+		{"client=claude", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			client, err := parseClient(values)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got client %q", client)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client != tt.expected {
+				t.Errorf("expected client %q, got %q", tt.expected, client)
+			}
+		})
+	}
+}
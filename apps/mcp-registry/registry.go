@@ -0,0 +1,599 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry serves the allowlist from an in-memory snapshot. The snapshot is
+// loaded and validated once, and replaced atomically whenever a valid change
+// to the store is detected.
+type Registry struct {
+	store    RegistryStore
+	config   *Config
+	metrics  *Metrics
+	upstream *Upstream
+	prober   *Prober
+
+	current atomic.Pointer[registrySnapshot]
+	// draining is set once the process starts shutting down, so the pod is
+	// taken out of rotation before the server stops.
+	draining atomic.Bool
+
+	mu sync.Mutex
+	// local is the last allowlist that passed validation, before mirrored
+	// servers are merged in, and localUpdatedAt its modification time.
+	local          *StaticRegistryData
+	localUpdatedAt time.Time
+	mirrored       []StaticServerData
+	mirroredAt     time.Time
+	syncRequests   chan struct{}
+	// served is the data of the current snapshot and servedAt its update
+	// time, kept so probe results can be applied without a reload.
+	served   *StaticRegistryData
+	servedAt time.Time
+	health   map[string]RemoteHealth
+}
+
+var (
+	errNotLoaded = errors.New("no valid allowlist has been loaded yet")
+	errDraining  = errors.New("shutting down")
+)
+
+type registrySnapshot struct {
+	servers []ServerResponse
+	// byName holds indexes into servers for each name, highest version first.
+	byName    map[string][]int
+	latest    map[string]int
+	updatedAt time.Time
+	// modifiedAt is the Last-Modified of responses: updatedAt, or later if
+	// probe results changed the content since.
+	modifiedAt time.Time
+	// etag is a strong validator derived from the served content, so it
+	// changes whenever any response built from this snapshot would.
+	etag string
+	// signed is the verified allowlist file the snapshot was built from,
+	// or nil if it is not signed.
+	signed *signedAllowlist
+}
+
+func NewRegistry(store RegistryStore, config *Config, metrics *Metrics) *Registry {
+	r := &Registry{
+		store:        store,
+		config:       config,
+		metrics:      metrics,
+		syncRequests: make(chan struct{}, 1),
+	}
+	if config.UpstreamURL != "" {
+		r.upstream = NewUpstream(config.UpstreamURL, nil)
+	}
+	if config.HealthProbeInterval > 0 {
+		r.prober = NewProber(nil)
+	}
+	return r
+}
+
+// Load reads and validates the entries in the store and swaps them in as the
+// current snapshot. On error the previous snapshot is kept.
+func (r *Registry) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, updatedAt, err := r.store.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := r.publish(data, updatedAt, r.mirrored, r.mirroredAt); err != nil {
+		return err
+	}
+
+	if data.Mirror != nil && r.upstream == nil {
+		slog.Warn("Allowlist has a mirror section, but no upstream registry is configured", "store", r.store.String())
+	}
+	if r.upstream != nil && r.local != nil && !slices.Equal(mirrorInclude(r.local), mirrorInclude(data)) {
+		r.requestSync()
+	}
+
+	r.local = data
+	r.localUpdatedAt = updatedAt
+	return nil
+}
+
+// publish merges mirrored servers into local, validates the result and swaps
+// it in as the current snapshot. The caller must hold r.mu.
+func (r *Registry) publish(local *StaticRegistryData, localUpdatedAt time.Time, mirrored []StaticServerData, mirroredAt time.Time) error {
+	merged := mergeMirrored(local, mirrored)
+	if err := validateRegistry(merged); err != nil {
+		return fmt.Errorf("allowlist with mirrored servers is invalid: %w", err)
+	}
+	mirroredCount := len(merged.Servers) - len(local.Servers)
+	merged = scopeServers(merged, r.config)
+	merged.signed = local.signed
+
+	updatedAt := localUpdatedAt
+	if mirroredAt.After(updatedAt) {
+		updatedAt = mirroredAt
+	}
+
+	snapshot := newRegistrySnapshot(merged, updatedAt, r.health)
+	r.current.Store(snapshot)
+	r.served = merged
+	r.servedAt = updatedAt
+	r.metrics.ObserveSnapshot(snapshot.servers, time.Now())
+	r.metrics.ObserveReviews(reviewExpiries(snapshot.servers, r.config.ReviewMaxAgeMonths))
+	r.metrics.ObserveHealth(snapshot.servers)
+
+	slog.Info("Loaded allowlist", "store", r.store.String(), "server_count", len(snapshot.servers), "mirrored_count", mirroredCount, "environment", r.config.Environment, "audience", r.config.Audience)
+	return nil
+}
+
+// Watch polls the store for changes until ctx is cancelled. A change
+// that fails validation is logged and the last good snapshot keeps serving.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if changed, err := r.store.Changed(ctx); err != nil {
+				slog.Warn("Cannot check store for changes", "store", r.store.String(), "error", err)
+				continue
+			} else if !changed {
+				continue
+			}
+			if err := r.Load(); err != nil {
+				r.metrics.ReloadFailed()
+				slog.Error("Rejected allowlist reload - keeping last good snapshot", "store", r.store.String(), "error", err)
+			}
+		}
+	}
+}
+
+// Mirror syncs servers from the upstream registry every interval, and
+// whenever the allowlist changes which servers to mirror, until ctx is
+// cancelled. It does nothing when no upstream registry is configured. A sync
+// that fails is logged and the last mirrored servers keep serving.
+func (r *Registry) Mirror(ctx context.Context, interval time.Duration) {
+	if r.upstream == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			r.metrics.UpstreamSyncFailed()
+			slog.Error("Upstream sync failed - keeping last mirrored servers", "upstream", r.config.UpstreamURL, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.syncRequests:
+		}
+	}
+}
+
+// Sync fetches the servers selected by the allowlist's mirror section from
+// the upstream registry and publishes them merged with the local entries.
+func (r *Registry) Sync(ctx context.Context) error {
+	r.mu.Lock()
+	var mirror *MirrorConfig
+	if r.local != nil {
+		mirror = r.local.Mirror
+	}
+	r.mu.Unlock()
+
+	var fetched []StaticServerData
+	if mirror != nil && len(mirror.Include) > 0 {
+		var err error
+		fetched, err = r.upstream.Fetch(ctx, mirror.Include)
+		if err != nil {
+			return err
+		}
+		fetched = validMirroredServers(fetched, mirror)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.local == nil {
+		return fmt.Errorf("allowlist not loaded")
+	}
+
+	now := time.Now()
+	if !reflect.DeepEqual(fetched, r.mirrored) {
+		mirroredAt := now.UTC().Truncate(time.Second)
+		if err := r.publish(r.local, r.localUpdatedAt, fetched, mirroredAt); err != nil {
+			return err
+		}
+		r.mirrored = fetched
+		r.mirroredAt = mirroredAt
+	}
+
+	r.metrics.UpstreamSynced(len(fetched), now)
+	return nil
+}
+
+// ProbeRemotes probes the remotes of every server that is not deleted every
+// interval until ctx is cancelled. It does nothing when probing is disabled.
+func (r *Registry) ProbeRemotes(ctx context.Context, interval time.Duration) {
+	if r.prober == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe runs one round of probes and serves the results. Remotes added while
+// the round runs get their health in the next round.
+func (r *Registry) probe(ctx context.Context) {
+	servers := r.Servers()
+	results := r.prober.ProbeAll(ctx, probeTargets(servers))
+	if ctx.Err() != nil {
+		return
+	}
+	logProbeResults(results)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.health = results
+	if r.served != nil {
+		snapshot := newRegistrySnapshot(r.served, r.servedAt, results)
+		snapshot.modifiedAt = probedModifiedAt(r.current.Load(), snapshot)
+		r.current.Store(snapshot)
+		r.metrics.ObserveHealth(snapshot.servers)
+	}
+	r.metrics.RemotesProbed(time.Now())
+}
+
+// probedModifiedAt returns the modification time of snapshot, rebuilt from
+// the same data as current with new probe results. The data has not
+// changed, but if the results changed the content, the time is moved past
+// the current one, so clients revalidating with If-Modified-Since get it.
+func probedModifiedAt(current, snapshot *registrySnapshot) time.Time {
+	if current == nil {
+		return snapshot.modifiedAt
+	}
+	if current.etag == snapshot.etag {
+		return current.modifiedAt
+	}
+	next := current.modifiedAt.Truncate(time.Second).Add(time.Second)
+	if now := time.Now(); now.After(next) {
+		return now
+	}
+	return next
+}
+
+func (r *Registry) requestSync() {
+	select {
+	case r.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+func mirrorInclude(data *StaticRegistryData) []string {
+	if data == nil || data.Mirror == nil {
+		return nil
+	}
+	return data.Mirror.Include
+}
+
+// Publish adds a new server version to the store and reloads, so it is
+// served right away.
+func (r *Registry) Publish(ctx context.Context, server StaticServerData) error {
+	if err := r.store.Put(ctx, server); err != nil {
+		return err
+	}
+	r.reloadAfterChange()
+	return nil
+}
+
+// SetStatus changes the status of a server version in the store and reloads,
+// so the change is served right away.
+func (r *Registry) SetStatus(ctx context.Context, name, version string, change statusChange) error {
+	if err := r.store.SetStatus(ctx, name, version, change); err != nil {
+		return err
+	}
+	r.reloadAfterChange()
+	return nil
+}
+
+// reloadAfterChange reloads after a write the store accepted. If that fails,
+// Watch picks the change up later, so the write is still reported as done.
+func (r *Registry) reloadAfterChange() {
+	if err := r.Load(); err != nil {
+		r.metrics.ReloadFailed()
+		slog.Error("Stored change could not be loaded yet", "store", r.store.String(), "error", err)
+	}
+}
+
+// Snapshot returns the current snapshot, or nil before the first successful
+// load. Handlers that answer from several lookups should use a single
+// snapshot, so a concurrent reload cannot mix two versions of the allowlist.
+func (r *Registry) Snapshot() *registrySnapshot {
+	return r.current.Load()
+}
+
+// Ready reports why the registry should not receive traffic: before the
+// first valid allowlist is loaded, and after Drain is called.
+func (r *Registry) Ready() error {
+	if r.draining.Load() {
+		return errDraining
+	}
+	if r.current.Load() == nil {
+		return errNotLoaded
+	}
+	return nil
+}
+
+// Drain marks the registry as shutting down. Requests are still served, but
+// Ready fails from now on.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Servers returns all servers in the current snapshot, ordered by name and
+// then highest version first. The returned slice is shared and must not be
+// modified.
+func (r *Registry) Servers() []ServerResponse {
+	return r.current.Load().Servers()
+}
+
+// Find returns the server with the given name and version. The version
+// "latest" resolves to the server's latest version.
+func (r *Registry) Find(name, version string) (ServerResponse, bool) {
+	return r.current.Load().Find(name, version)
+}
+
+// Versions returns every version of the named server, highest version first.
+func (r *Registry) Versions(name string) []ServerResponse {
+	return r.current.Load().Versions(name)
+}
+
+func (s *registrySnapshot) Servers() []ServerResponse {
+	if s == nil {
+		return nil
+	}
+	return s.servers
+}
+
+func (s *registrySnapshot) Find(name, version string) (ServerResponse, bool) {
+	if s == nil {
+		return ServerResponse{}, false
+	}
+
+	if version == VersionLatest {
+		i, ok := s.latest[name]
+		if !ok {
+			return ServerResponse{}, false
+		}
+		return s.servers[i], true
+	}
+
+	for _, i := range s.byName[name] {
+		if s.servers[i].Server.Version == version {
+			return s.servers[i], true
+		}
+	}
+	return ServerResponse{}, false
+}
+
+func (s *registrySnapshot) Versions(name string) []ServerResponse {
+	if s == nil {
+		return nil
+	}
+
+	indexes := s.byName[name]
+	versions := make([]ServerResponse, 0, len(indexes))
+	for _, i := range indexes {
+		versions = append(versions, s.servers[i])
+	}
+	return versions
+}
+
+func newRegistrySnapshot(data *StaticRegistryData, updatedAt time.Time, health map[string]RemoteHealth) *registrySnapshot {
+	snapshot := &registrySnapshot{
+		servers:    make([]ServerResponse, 0, len(data.Servers)),
+		byName:     make(map[string][]int),
+		latest:     make(map[string]int),
+		updatedAt:  updatedAt,
+		modifiedAt: updatedAt,
+		signed:     data.signed,
+	}
+
+	for i := range data.Servers {
+		snapshot.servers = append(snapshot.servers, newServerResponse(&data.Servers[i], updatedAt))
+		addHealth(&snapshot.servers[i], health)
+	}
+
+	sort.SliceStable(snapshot.servers, func(a, b int) bool {
+		return compareServerKeys(keyOf(&snapshot.servers[a]), keyOf(&snapshot.servers[b])) < 0
+	})
+
+	for i := range snapshot.servers {
+		name := snapshot.servers[i].Server.Name
+		snapshot.byName[name] = append(snapshot.byName[name], i)
+	}
+
+	for name, indexes := range snapshot.byName {
+		if i, ok := latestVersion(snapshot.servers, indexes); ok {
+			snapshot.latest[name] = i
+			snapshot.servers[i].Meta.Official.IsLatest = true
+		}
+	}
+
+	snapshot.etag = contentETag(snapshot.servers)
+
+	return snapshot
+}
+
+// contentETag hashes the JSON encoding of servers into a quoted ETag value.
+func contentETag(servers []ServerResponse) string {
+	data, err := json.Marshal(servers)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func keyOf(s *ServerResponse) serverKey {
+	return serverKey{name: s.Server.Name, version: s.Server.Version}
+}
+
+// compareServerKeys defines the order servers are listed in: by name, and
+// then by version with the highest version first.
+func compareServerKeys(a, b serverKey) int {
+	if c := strings.Compare(a.name, b.name); c != 0 {
+		return c
+	}
+	if c := compareVersions(a.version, b.version); c != 0 {
+		return -c
+	}
+	return strings.Compare(a.version, b.version)
+}
+
+// latestVersion picks the highest active version from indexes, which must be
+// sorted highest version first. When no version is active the highest
+// deprecated version is used, and deleted versions are never latest.
+func latestVersion(servers []ServerResponse, indexes []int) (int, bool) {
+	for _, status := range []string{StatusActive, StatusDeprecated} {
+		for _, i := range indexes {
+			if servers[i].Meta.Official.Status == status {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func newServerResponse(s *StaticServerData, updatedAt time.Time) ServerResponse {
+	publishedAt := updatedAt
+	if s.PublishedAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, s.PublishedAt); err == nil {
+			publishedAt = parsed
+		}
+	}
+
+	status := s.Status
+	if status == "" {
+		status = StatusActive
+	}
+
+	return ServerResponse{
+		Server: ServerJSON{
+			Schema:      CurrentSchemaURL,
+			Name:        s.Name,
+			Description: s.Description,
+			Version:     s.Version,
+			Repository:  s.Repository,
+			Packages:    s.Packages,
+			Remotes:     s.Remotes,
+		},
+		Meta: ResponseMeta{
+			Official: &RegistryExtensions{
+				Status:      status,
+				PublishedAt: publishedAt,
+				UpdatedAt:   updatedAt,
+			},
+			Nav: navExtensions(s, status),
+		},
+	}
+}
+
+// navExtensions returns the Nav specific metadata of a server, or nil if
+// there is none.
+func navExtensions(s *StaticServerData, status string) *NavExtensions {
+	nav := NavExtensions{
+		Environments: s.Environments,
+		Audience:     s.Audience,
+	}
+
+	if status != StatusActive {
+		message := s.DeprecationMessage
+		if message == "" {
+			message = fmt.Sprintf("Version %s of %s is %s", s.Version, s.Name, status)
+		}
+		nav.Deprecation = &Deprecation{
+			Message:    message,
+			ReplacedBy: s.ReplacedBy,
+		}
+	}
+
+	nav.Ownership = newOwnership(s)
+	nav.Review = s.Review
+
+	if reflect.ValueOf(nav).IsZero() {
+		return nil
+	}
+	return &nav
+}
+
+// newOwnership returns who to contact about a server, or nil if the entry
+// does not say.
+func newOwnership(s *StaticServerData) *Ownership {
+	ownership := Ownership{
+		Owner:         s.Owner,
+		Contact:       s.Contact,
+		Documentation: s.Documentation,
+	}
+	if s.Owner != "" {
+		ownership.OwnerURL = OwnerTeamURL + s.Owner
+	}
+	if s.Repository != nil {
+		ownership.Repository = s.Repository.URL
+	}
+	if ownership == (Ownership{}) {
+		return nil
+	}
+	return &ownership
+}
+
+// addHealth adds the last probe result of each remote of s to its Nav
+// extensions. Deleted versions are not probed.
+func addHealth(s *ServerResponse, health map[string]RemoteHealth) {
+	if s.Meta.Official.Status == StatusDeleted {
+		return
+	}
+	for _, remote := range s.Server.Remotes {
+		result, ok := health[remote.URL]
+		if !ok {
+			continue
+		}
+		if s.Meta.Nav == nil {
+			s.Meta.Nav = &NavExtensions{}
+		}
+		s.Meta.Nav.Health = append(s.Meta.Nav.Health, result)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const registryTestAllowlist = `{
+  "servers": [
+    {
+      "name": "io.github.test/server",
+      "description": "Test Description",
+      "owner": "team-test",
+      "version": "1.0.0",
+      "remotes": [{ "type": "streamable-http", "url": "https://server.{{domain_internal}}/mcp" }]
+    }
+  ]
+}`
+
+func writeAllowlist(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write allowlist: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set allowlist mtime: %v", err)
+	}
+}
+
+func loadTestRegistry(t *testing.T, content string, modTime time.Time) *Registry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, content, modTime)
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load test allowlist: %v", err)
+	}
+	return registry
+}
+
+func waitFor(t *testing.T, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRegistry_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	writeAllowlist(t, path, registryTestAllowlist, modTime)
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	servers := registry.Servers()
+	if len(servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(servers))
+	}
+
+	if url := servers[0].Server.Remotes[0].URL; url != "https://server.intern.dev.nav.no/mcp" {
+		t.Errorf("expected substituted url, got %s", url)
+	}
+
+	if !servers[0].Meta.Official.UpdatedAt.Equal(modTime) {
+		t.Errorf("expected updatedAt %s, got %s", modTime, servers[0].Meta.Official.UpdatedAt)
+	}
+
+	if !servers[0].Meta.Official.PublishedAt.Equal(modTime) {
+		t.Errorf("expected publishedAt to default to updatedAt, got %s", servers[0].Meta.Official.PublishedAt)
+	}
+
+	if servers[0].Meta.Official.Status != StatusActive {
+		t.Errorf("expected status to default to active, got %s", servers[0].Meta.Official.Status)
+	}
+}
+
+func TestRegistry_LoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, `{"servers": []}`, time.Now())
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err == nil {
+		t.Fatal("expected error for empty registry, got nil")
+	}
+
+	if registry.Servers() != nil {
+		t.Error("expected no snapshot after failed initial load")
+	}
+}
+
+func TestRegistry_LoadMissingFile(t *testing.T) {
+	registry := NewRegistry(newFileStore(filepath.Join(t.TempDir(), "missing.json"), testConfig()), testConfig(), nil)
+	if err := registry.Load(); err == nil {
+		t.Fatal("expected error for missing file, got nil")
+	}
+}
+
+func TestRegistry_Find(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, registryTestAllowlist, time.Now())
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		server  string
+		version string
+		found   bool
+	}{
+		{"latest", "io.github.test/server", "latest", true},
+		{"exact version", "io.github.test/server", "1.0.0", true},
+		{"unknown version", "io.github.test/server", "2.0.0", false},
+		{"unknown server", "io.github.test/other", "latest", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, found := registry.Find(tt.server, tt.version)
+			if found != tt.found {
+				t.Errorf("expected found=%v, got %v", tt.found, found)
+			}
+		})
+	}
+}
+
+func TestRegistry_WatchReloadsValidChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	modTime := time.Now().Add(-time.Hour)
+	writeAllowlist(t, path, registryTestAllowlist, modTime)
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, 10*time.Millisecond)
+
+	updated := `{"servers": [
+		{"name": "io.github.test/server", "owner": "team-test", "description": "Test Description", "version": "1.0.0"},
+		{"name": "io.github.test/other", "owner": "team-test", "description": "Other Description", "version": "1.0.0"}
+	]}`
+	writeAllowlist(t, path, updated, modTime.Add(time.Minute))
+
+	if !waitFor(t, func() bool { return len(registry.Servers()) == 2 }) {
+		t.Fatalf("expected reload to pick up 2 servers, got %d", len(registry.Servers()))
+	}
+
+	if _, found := registry.Find("io.github.test/other", "latest"); !found {
+		t.Error("expected new server to be found after reload")
+	}
+}
+
+func TestRegistry_WatchRejectsInvalidChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	modTime := time.Now().Add(-time.Hour)
+	writeAllowlist(t, path, registryTestAllowlist, modTime)
+
+	store := newFileStore(path, testConfig())
+	registry := NewRegistry(store, testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, 10*time.Millisecond)
+
+	writeAllowlist(t, path, `{"servers": [{"name": "invalid"}]}`, modTime.Add(time.Minute))
+
+	if !waitFor(t, func() bool { changed, _ := store.Changed(ctx); return !changed }) {
+		t.Fatal("expected watcher to process the change")
+	}
+
+	servers := registry.Servers()
+	if len(servers) != 1 || servers[0].Server.Name != "io.github.test/server" {
+		t.Errorf("expected last good snapshot to keep serving, got %+v", servers)
+	}
+}
+
+func TestRegistry_MultipleVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, `{"servers": [
+		{"name": "io.github.test/server", "owner": "team-test", "description": "v1", "version": "1.2.0"},
+		{"name": "io.github.test/server", "owner": "team-test", "description": "v10", "version": "1.10.0"},
+		{"name": "io.github.test/server", "description": "v2 rc", "version": "2.0.0-rc.1", "status": "deleted"},
+		{"name": "io.github.test/server", "owner": "team-test", "description": "v1 old", "version": "1.0.0", "status": "deprecated"},
+		{"name": "io.github.test/legacy", "owner": "team-test", "description": "legacy", "version": "1.0.0", "status": "deprecated"},
+		{"name": "io.github.test/legacy", "owner": "team-test", "description": "legacy", "version": "0.9.0", "status": "deprecated"},
+		{"name": "io.github.test/gone", "description": "gone", "version": "1.0.0", "status": "deleted"}
+	]}`, time.Now())
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), nil)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	latest, found := registry.Find("io.github.test/server", VersionLatest)
+	if !found {
+		t.Fatal("expected latest version to be found")
+	}
+	if latest.Server.Version != "1.10.0" {
+		t.Errorf("expected latest to be highest active version 1.10.0, got %s", latest.Server.Version)
+	}
+
+	legacy, found := registry.Find("io.github.test/legacy", VersionLatest)
+	if !found || legacy.Server.Version != "1.0.0" {
+		t.Errorf("expected latest to fall back to highest deprecated version, got %+v", legacy.Server)
+	}
+
+	if _, found := registry.Find("io.github.test/gone", VersionLatest); found {
+		t.Error("expected no latest version when every version is deleted")
+	}
+
+	versions := registry.Versions("io.github.test/server")
+	expected := []string{"2.0.0-rc.1", "1.10.0", "1.2.0", "1.0.0"}
+	if len(versions) != len(expected) {
+		t.Fatalf("expected %d versions, got %d", len(expected), len(versions))
+	}
+	for i, v := range versions {
+		if v.Server.Version != expected[i] {
+			t.Errorf("versions[%d]: expected %s, got %s", i, expected[i], v.Server.Version)
+		}
+		if isLatest := v.Server.Version == "1.10.0"; v.Meta.Official.IsLatest != isLatest {
+			t.Errorf("versions[%d]: expected isLatest=%v, got %v", i, isLatest, v.Meta.Official.IsLatest)
+		}
+	}
+
+	latestCount := 0
+	for _, s := range registry.Servers() {
+		if s.Meta.Official.IsLatest {
+			latestCount++
+		}
+	}
+	if latestCount != 2 {
+		t.Errorf("expected exactly one latest entry per server with a live version, got %d", latestCount)
+	}
+}
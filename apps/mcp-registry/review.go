@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// expiresAt returns when the review of a high-risk server expires, which is
+// maxAgeMonths after it was reviewed. It reports false for other risk levels
+// and reviews without a valid date.
+func (r *Review) expiresAt(maxAgeMonths int) (time.Time, bool) {
+	if r == nil || r.Risk != RiskHigh {
+		return time.Time{}, false
+	}
+	reviewedAt, err := time.Parse(time.DateOnly, r.ReviewedAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return reviewedAt.AddDate(0, maxAgeMonths, 0), true
+}
+
+// reviewWarnings warns about high-risk servers whose review has expired at
+// now. Deleted versions cannot be used, so they are not reviewed.
+func reviewWarnings(data *StaticRegistryData, maxAgeMonths int, now time.Time) []error {
+	var warnings []error
+	for i := range data.Servers {
+		server := &data.Servers[i]
+		if server.Status == StatusDeleted {
+			continue
+		}
+		if expiresAt, ok := server.Review.expiresAt(maxAgeMonths); ok && !now.Before(expiresAt) {
+			warnings = append(warnings, fmt.Errorf("server[%d]: the review of %s risk server '%s' from %s expired on %s, it must be reviewed every %d months",
+				i, RiskHigh, server.Name, server.Review.ReviewedAt, expiresAt.Format(time.DateOnly), maxAgeMonths))
+		}
+	}
+	return warnings
+}
+
+// reviewExpiries returns when the review of each high-risk server that is
+// not deleted expires.
+func reviewExpiries(servers []ServerResponse, maxAgeMonths int) []time.Time {
+	var expiries []time.Time
+	for i := range servers {
+		s := &servers[i]
+		if s.Meta.Official.Status == StatusDeleted || s.Meta.Nav == nil {
+			continue
+		}
+		if expiresAt, ok := s.Meta.Nav.Review.expiresAt(maxAgeMonths); ok {
+			expiries = append(expiries, expiresAt)
+		}
+	}
+	return expiries
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewWarnings(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		server   StaticServerData
+		expected string
+	}{
+		{"not reviewed", StaticServerData{}, ""},
+		{"high risk reviewed recently", StaticServerData{Review: &Review{Risk: RiskHigh, ReviewedAt: "2025-01-15"}}, ""},
+		{"high risk expires today", StaticServerData{Review: &Review{Risk: RiskHigh, ReviewedAt: "2024-06-01"}}, "expired on 2025-06-01"},
+		{"high risk expired", StaticServerData{Review: &Review{Risk: RiskHigh, ReviewedAt: "2023-01-01"}}, "it must be reviewed every 12 months"},
+		{"medium risk reviewed long ago", StaticServerData{Review: &Review{Risk: RiskMedium, ReviewedAt: "2020-01-01"}}, ""},
+		{"deleted high risk expired", StaticServerData{Status: StatusDeleted, Review: &Review{Risk: RiskHigh, ReviewedAt: "2023-01-01"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Name = "io.github.test/server"
+			warnings := reviewWarnings(&StaticRegistryData{Servers: []StaticServerData{tt.server}}, 12, now)
+
+			if tt.expected == "" {
+				if len(warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), tt.expected) {
+				t.Errorf("expected warning containing %q, got %v", tt.expected, warnings)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_ExpiredReviews(t *testing.T) {
+	metrics := NewMetrics()
+	reviewedAt := time.Now().AddDate(0, -13, 0).Format(time.DateOnly)
+	recentlyReviewedAt := time.Now().AddDate(0, -1, 0).Format(time.DateOnly)
+	path := t.TempDir() + "/allowlist.json"
+	writeAllowlist(t, path, `{"servers": [
+		{"name": "io.github.test/expired", "owner": "team-test", "description": "Expired", "version": "1.0.0",
+			"review": {"risk": "high", "reviewedAt": "`+reviewedAt+`", "reviewer": "appsec"}},
+		{"name": "io.github.test/expired", "description": "Expired", "version": "0.9.0", "status": "deleted",
+			"review": {"risk": "high", "reviewedAt": "`+reviewedAt+`", "reviewer": "appsec"}},
+		{"name": "io.github.test/current", "owner": "team-test", "description": "Current", "version": "1.0.0",
+			"review": {"risk": "high", "reviewedAt": "`+recentlyReviewedAt+`", "reviewer": "appsec"}},
+		{"name": "io.github.test/low", "owner": "team-test", "description": "Low", "version": "1.0.0",
+			"review": {"risk": "low"}}
+	]}`, time.Now())
+
+	registry := NewRegistry(newFileStore(path, testConfig()), testConfig(), metrics)
+	if err := registry.Load(); err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+
+	assertMetric(t, scrapeMetrics(t, metrics), "mcp_registry_expired_reviews 1")
+
+	// Reviews expire while the allowlist is served, without a reload.
+	soon := []time.Time{time.Now().Add(-time.Minute), time.Now().Add(-time.Second), time.Now().Add(time.Hour)}
+	metrics.ObserveReviews(soon)
+	assertMetric(t, scrapeMetrics(t, metrics), "mcp_registry_expired_reviews 2")
+}
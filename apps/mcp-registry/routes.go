@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/navikt/copilot/libs/problem"
+)
+
+// newRouter returns the HTTP routes of the registry. auth is nil when the
+// admin API is disabled.
+func newRouter(config *Config, registry *Registry, metrics *Metrics, auth *Authenticator) http.Handler {
+	mux := http.NewServeMux()
+	meter := func(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+		return metricsMiddleware(metrics, pattern, loggingMiddleware(config, handler))
+	}
+	handle := func(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+		handler = meter(pattern, handler)
+		mux.HandleFunc(pattern, handler)
+		return handler
+	}
+
+	handle("GET /health", healthHandler)
+	handle("GET /ready", makeReadyHandler(registry))
+	handle("GET /metrics", metrics.Handler().ServeHTTP)
+	handle("GET /v0.1/servers", makeServersListHandler(registry))
+	versions := handle("GET /v0.1/servers/{name}/versions", makeServerVersionsHandler(registry, metrics))
+	const versionPattern = "GET /v0.1/servers/{name}/versions/{version}"
+	const configPattern = "GET /v0.1/servers/{name}/versions/{version}/config"
+	version := meter(versionPattern, makeServerVersionHandler(registry, metrics))
+	serverConfig := meter(configPattern, makeServerConfigHandler(registry, metrics))
+	handle("GET /v0.1/signature", makeSignatureHandler(registry))
+	handle("GET /{$}", makeRootHandler(registry))
+
+	if auth != nil {
+		handle("POST /v0.1/publish", requireAdmin(auth, makePublishHandler(registry)))
+		status := handle("PATCH /v0.1/servers/{name}/versions/{version}/status", requireAdmin(auth, makeStatusHandler(registry)))
+		remove := handle("DELETE /v0.1/servers/{name}/versions/{version}", requireAdmin(auth, makeDeleteHandler(registry)))
+		// Unlike the read routes, these never overlap with the encoded
+		// form, so the raw name can be matched by the mux.
+		mux.HandleFunc("PATCH /v0.1/servers/{org}/{server}/versions/{version}/status", rawName(status))
+		mux.HandleFunc("DELETE /v0.1/servers/{org}/{server}/versions/{version}", rawName(remove))
+	}
+
+	// The handlers are metered and logged already, so the raw name routes
+	// are not.
+	rawNames := makeRawNameHandler(map[string]http.HandlerFunc{
+		"versions":                  versions,
+		"versions/{version}":        version,
+		"versions/{version}/config": serverConfig,
+	})
+	mux.HandleFunc(versionPattern, rawNameFirst(rawNames, "", version))
+	mux.HandleFunc(configPattern, rawNameFirst(rawNames, "/config", serverConfig))
+	mux.HandleFunc("GET /v0.1/servers/{path...}", rawNames)
+
+	return securityHeadersMiddleware(corsMiddleware(config, routeProblems(mux, metrics)))
+}
+
+// makeRawNameHandler serves server paths with the slash in the server name
+// not encoded, such as /v0.1/servers/io.github.navikt/github-mcp/versions.
+// Server names have exactly one slash, so the name is always the first two
+// segments. The rest of the path selects a handler from routes, keyed by
+// the route after the name, which is called with the name and version as
+// path values. Any other path is not found.
+func makeRawNameHandler(routes map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		segments := strings.Split(r.PathValue("path"), "/")
+		if len(segments) < 3 || segments[0] == "" || segments[1] == "" || segments[2] != "versions" {
+			slog.Warn("Invalid path format", "path", r.URL.Path)
+			problem.Write(w, r, problemInvalidPath.New("Expected /v0.1/servers/{serverName}/versions, optionally followed by /{version} or /{version}/config"))
+			return
+		}
+
+		route := segments[2:]
+		if len(route) > 1 {
+			r.SetPathValue("version", route[1])
+			route[1] = "{version}"
+		}
+		handler, ok := routes[strings.Join(route, "/")]
+		if !ok || len(route) > 1 && r.PathValue("version") == "" {
+			problem.Write(w, r, problem.Status(http.StatusNotFound).New(fmt.Sprintf("There is no endpoint at %s", r.URL.EscapedPath())))
+			return
+		}
+
+		r.SetPathValue("name", segments[0]+"/"+segments[1])
+		handler(w, r)
+	}
+}
+
+// rawNameFirst serves the paths of a route that are a raw name whose server
+// part is "versions", such as /v0.1/servers/io.github.navikt/versions/versions,
+// with rawNames. The route matches them with a name without a slash and the
+// version "versions", but server names always have a slash, so only the raw
+// reading can be right. suffix is what the route has after the version.
+func rawNameFirst(rawNames http.HandlerFunc, suffix string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if strings.Contains(name, "/") || r.PathValue("version") != "versions" {
+			handler(w, r)
+			return
+		}
+		r.SetPathValue("path", name+"/versions/versions"+suffix)
+		rawNames(w, r)
+	}
+}
+
+// rawName serves a route with the raw server name matched by the {org} and
+// {server} wildcards, with the name as the {name} path value.
+func rawName(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue("name", r.PathValue("org")+"/"+r.PathValue("server"))
+		handler(w, r)
+	}
+}
+
+// routeProblems writes problem details for requests that match no route,
+// instead of the plain text errors of http.ServeMux. They are metered as
+// the unmatched route.
+func routeProblems(mux *http.ServeMux, metrics *Metrics) http.HandlerFunc {
+	unmatched := metricsMiddleware(metrics, "unmatched", func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(&routeErrorWriter{ResponseWriter: w, r: r}, r)
+	})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern == "" {
+			unmatched(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}
+}
+
+// routeErrorWriter replaces the 404 and 405 errors of http.ServeMux with
+// problem details. The Allow header the mux sets for a 405 is kept.
+type routeErrorWriter struct {
+	http.ResponseWriter
+	r *http.Request
+}
+
+func (w *routeErrorWriter) WriteHeader(status int) {
+	detail := fmt.Sprintf("There is no endpoint at %s", w.r.URL.EscapedPath())
+	if status == http.StatusMethodNotAllowed {
+		detail = fmt.Sprintf("%s is not supported, use %s", w.r.Method, w.Header().Get("Allow"))
+	}
+	problem.Write(w.ResponseWriter, w.r, problem.Status(status).New(detail))
+}
+
+func (w *routeErrorWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
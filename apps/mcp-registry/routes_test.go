@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const routesTestAllowlist = `{"servers": [
+	{"name": "io.github.test/server", "owner": "team-test", "description": "Server", "version": "1.0.0",
+		"remotes": [{"type": "streamable-http", "url": "https://server.intern.nav.no/mcp"}]},
+	{"name": "io.github.test/versions", "owner": "team-test", "description": "Server named versions", "version": "2.0.0"}
+]}`
+
+func TestRouter(t *testing.T) {
+	registry := loadTestRegistry(t, routesTestAllowlist, time.Now())
+	router := newRouter(registry.config, registry, NewMetrics(), nil)
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedStatus  int
+		expectedName    string
+		expectedVersion string
+		expectedAllow   string
+	}{
+		{"encoded name", http.MethodGet, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", http.StatusOK, "io.github.test/server", "1.0.0", ""},
+		{"raw name", http.MethodGet, "/v0.1/servers/io.github.test/server/versions/1.0.0", http.StatusOK, "io.github.test/server", "1.0.0", ""},
+		{"raw name latest", http.MethodGet, "/v0.1/servers/io.github.test/server/versions/latest", http.StatusOK, "io.github.test/server", "1.0.0", ""},
+		{"raw name versions", http.MethodGet, "/v0.1/servers/io.github.test/server/versions", http.StatusOK, "", "", ""},
+		{"raw name config", http.MethodGet, "/v0.1/servers/io.github.test/server/versions/1.0.0/config", http.StatusOK, "", "", ""},
+		{"encoded name with versions", http.MethodGet, "/v0.1/servers/io.github.test%2Fversions/versions/2.0.0", http.StatusOK, "io.github.test/versions", "2.0.0", ""},
+		{"raw name with versions", http.MethodGet, "/v0.1/servers/io.github.test/versions/versions/2.0.0", http.StatusOK, "io.github.test/versions", "2.0.0", ""},
+		{"raw name with versions list", http.MethodGet, "/v0.1/servers/io.github.test/versions/versions", http.StatusOK, "", "", ""},
+		{"raw name with versions latest", http.MethodGet, "/v0.1/servers/io.github.test/versions/versions/latest", http.StatusOK, "io.github.test/versions", "2.0.0", ""},
+		{"encoded name with versions list", http.MethodGet, "/v0.1/servers/io.github.test%2Fversions/versions", http.StatusOK, "", "", ""},
+		{"head", http.MethodHead, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", http.StatusOK, "", "", ""},
+		{"root", http.MethodGet, "/", http.StatusOK, "", "", ""},
+		{"invalid server path", http.MethodGet, "/v0.1/servers/invalid-path", http.StatusBadRequest, "", "", ""},
+		{"raw name deep path", http.MethodGet, "/v0.1/servers/a/b/versions/x/y/z", http.StatusNotFound, "", "", ""},
+		{"raw name unknown subresource", http.MethodGet, "/v0.1/servers/io.github.test/server/versions/1.0.0/unknown", http.StatusNotFound, "", "", ""},
+		{"raw name empty version", http.MethodGet, "/v0.1/servers/io.github.test/server/versions/", http.StatusNotFound, "", "", ""},
+		{"unknown path", http.MethodGet, "/unknown", http.StatusNotFound, "", "", ""},
+		{"list method not allowed", http.MethodPost, "/v0.1/servers", http.StatusMethodNotAllowed, "", "", "GET, HEAD"},
+		{"admin disabled", http.MethodDelete, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", http.StatusMethodNotAllowed, "", "", "GET, HEAD"},
+		{"preflight", http.MethodOptions, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", http.StatusNoContent, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Accept", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if cors := w.Header().Get("Access-Control-Allow-Origin"); cors != "*" {
+				t.Errorf("expected CORS headers on every response, got %q", cors)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("expected Allow %q, got %q", tt.expectedAllow, allow)
+			}
+			if w.Code >= http.StatusBadRequest {
+				if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+					t.Errorf("expected problem details, got %s: %s", contentType, w.Body.String())
+				}
+			}
+
+			if tt.expectedName == "" {
+				return
+			}
+			var response ServerResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if response.Server.Name != tt.expectedName || response.Server.Version != tt.expectedVersion {
+				t.Errorf("expected %s %s, got %s %s", tt.expectedName, tt.expectedVersion, response.Server.Name, response.Server.Version)
+			}
+		})
+	}
+}
+
+func TestRouter_Admin(t *testing.T) {
+	registry := loadTestRegistry(t, routesTestAllowlist, time.Now())
+	config := testConfig()
+	config.AdminToken = "secret"
+	router := newRouter(config, registry, NewMetrics(), newTestAuthenticator(t, config))
+
+	req := httptest.NewRequest(http.MethodPut, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD" {
+		t.Errorf("expected Allow to list the admin method, got %q", allow)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/v0.1/servers/io.github.test%2Fserver/versions/1.0.0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected admin route to require a token, got %d", w.Code)
+	}
+}
+
+func TestRouter_AdminRawName(t *testing.T) {
+	registry, _, _ := newAdminTestServer(t)
+	handler := newRouter(registry.config, registry, NewMetrics(), newTestAuthenticator(t, registry.config))
+
+	w := adminRequest(t, handler, http.MethodPatch, "/v0.1/servers/io.github.navikt/existing/versions/1.0.0/status", `{"status": "deprecated"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if server, _ := registry.Find("io.github.navikt/existing", "1.0.0"); server.Meta.Official.Status != StatusDeprecated {
+		t.Errorf("expected registry to serve deprecated status, got %s", server.Meta.Official.Status)
+	}
+
+	w = adminRequest(t, handler, http.MethodDelete, "/v0.1/servers/io.github.navikt/existing/versions/1.0.0", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if server, _ := registry.Find("io.github.navikt/existing", "1.0.0"); server.Meta.Official.Status != StatusDeleted {
+		t.Errorf("expected entry to be deleted, got %s", server.Meta.Official.Status)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/v0.1/servers/io.github.navikt/existing/versions/1.0.0", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected raw name admin route to require a token, got %d", w.Code)
+	}
+}
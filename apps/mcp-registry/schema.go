@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemaFiles holds vendored copies of the official server.json schemas, so
+// validation never needs network access.
+//
+//go:embed schemas
+var schemaFiles embed.FS
+
+// knownSchemas maps each supported $schema URL to its vendored file.
+var knownSchemas = map[string]string{
+	CurrentSchemaURL: "schemas/2025-12-11/server.schema.json",
+}
+
+// schemaViolation is a single schema error located by a JSON pointer into the
+// allowlist document, such as /servers/3/remotes/0/url.
+type schemaViolation struct {
+	Pointer string
+	Message string
+}
+
+func (v schemaViolation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Pointer, v.Message)
+}
+
+var compileSchemas = sync.OnceValues(func() (map[string]*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	for url, file := range knownSchemas {
+		data, err := schemaFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read vendored schema %s: %v", file, err)
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid vendored schema %s: %v", file, err)
+		}
+		if err := compiler.AddResource(url, doc); err != nil {
+			return nil, fmt.Errorf("cannot add vendored schema %s: %v", file, err)
+		}
+	}
+
+	schemas := make(map[string]*jsonschema.Schema, len(knownSchemas))
+	for url := range knownSchemas {
+		schema, err := compiler.Compile(url)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile schema %s: %v", url, err)
+		}
+		schemas[url] = schema
+	}
+	return schemas, nil
+})
+
+// validateSchema validates every server entry in the allowlist document
+// against the server.json schema named by its $schema field. Entries without
+// $schema are validated against the current schema.
+func validateSchema(data []byte) ([]schemaViolation, error) {
+	schemas, err := compileSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON format: %v", err)
+	}
+
+	root, ok := doc.(map[string]any)
+	if !ok {
+		return []schemaViolation{{Pointer: "", Message: "allowlist must be a JSON object"}}, nil
+	}
+	servers, ok := root["servers"].([]any)
+	if !ok {
+		return []schemaViolation{{Pointer: "/servers", Message: "'servers' must be an array"}}, nil
+	}
+
+	var violations []schemaViolation
+	for i, server := range servers {
+		pointer := fmt.Sprintf("/servers/%d", i)
+
+		schemaURL := CurrentSchemaURL
+		if entry, ok := server.(map[string]any); ok {
+			if s, ok := entry["$schema"].(string); ok && s != "" {
+				schemaURL = s
+			}
+		}
+
+		schema, ok := schemas[schemaURL]
+		if !ok {
+			violations = append(violations, schemaViolation{
+				Pointer: pointer + "/$schema",
+				Message: fmt.Sprintf("unknown schema version '%s', supported: %s", schemaURL, strings.Join(supportedSchemas(), ", ")),
+			})
+			continue
+		}
+
+		err := schema.Validate(server)
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			violations = append(violations, flattenViolations(pointer, validationErr)...)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return violations, nil
+}
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// flattenViolations reports the leaf errors of a validation error tree, which
+// are the ones that point at the offending value.
+func flattenViolations(pointer string, err *jsonschema.ValidationError) []schemaViolation {
+	var violations []schemaViolation
+	seen := make(map[schemaViolation]bool)
+
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+		v := schemaViolation{
+			Pointer: pointer + jsonPointer(e.InstanceLocation),
+			Message: e.ErrorKind.LocalizedString(schemaPrinter),
+		}
+		if !seen[v] {
+			seen[v] = true
+			violations = append(violations, v)
+		}
+	}
+	walk(err)
+
+	return violations
+}
+
+func jsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return sb.String()
+}
+
+func supportedSchemas() []string {
+	urls := make([]string, 0, len(knownSchemas))
+	for url := range knownSchemas {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema_AllowlistFile(t *testing.T) {
+	data, err := os.ReadFile("allowlist.json")
+	if err != nil {
+		t.Fatalf("failed to read allowlist.json: %v", err)
+	}
+
+	violations, err := validateSchema(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(violations) != 0 {
+		t.Errorf("expected allowlist.json to match the schema, got %v", violations)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		pointer  string
+		message  string
+	}{
+		{
+			name:     "valid entry without $schema",
+			document: `{"servers": [{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0"}]}`,
+		},
+		{
+			name: "valid entry with registry extensions",
+			document: `{"servers": [{"$schema": "` + CurrentSchemaURL + `", "name": "io.github.test/server",
+				"description": "Test", "version": "1.0.0", "status": "active", "publishedAt": "2025-01-01T00:00:00Z"}]}`,
+		},
+		{
+			name: "remote url pattern",
+			document: `{"servers": [
+				{"name": "io.github.test/first", "owner": "team-test", "description": "Test", "version": "1.0.0"},
+				{"name": "io.github.test/second", "owner": "team-test", "description": "Test", "version": "1.0.0",
+					"remotes": [{"type": "streamable-http", "url": "ftp://example.com/mcp"}]}
+			]}`,
+			pointer: "/servers/1/remotes/0/url",
+			message: "does not match pattern",
+		},
+		{
+			name:     "missing required field",
+			document: `{"servers": [{"name": "io.github.test/server", "version": "1.0.0"}]}`,
+			pointer:  "/servers/0",
+			message:  "missing property 'description'",
+		},
+		{
+			name: "package missing transport",
+			document: `{"servers": [{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0",
+				"packages": [{"registryType": "npm", "identifier": "pkg", "version": "1.0.0"}]}]}`,
+			pointer: "/servers/0/packages/0",
+			message: "missing property 'transport'",
+		},
+		{
+			name: "description too long",
+			document: `{"servers": [{"name": "io.github.test/server", "version": "1.0.0",
+				"description": "` + strings.Repeat("x", 101) + `"}]}`,
+			pointer: "/servers/0/description",
+			message: "maxLength",
+		},
+		{
+			name: "unknown schema version",
+			document: `{"servers": [{"$schema": "https://static.modelcontextprotocol.io/schemas/2024-01-01/server.schema.json",
+				"name": "io.github.test/server", "description": "Test", "version": "1.0.0"}]}`,
+			pointer: "/servers/0/$schema",
+			message: "unknown schema version",
+		},
+		{
+			name:     "servers not an array",
+			document: `{"servers": {}}`,
+			pointer:  "/servers",
+			message:  "must be an array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := validateSchema([]byte(tt.document))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if tt.pointer == "" {
+				if len(violations) != 0 {
+					t.Errorf("expected no violations, got %v", violations)
+				}
+				return
+			}
+
+			for _, v := range violations {
+				if v.Pointer == tt.pointer && strings.Contains(v.Message, tt.message) {
+					return
+				}
+			}
+			t.Errorf("expected violation at %s containing '%s', got %v", tt.pointer, tt.message, violations)
+		})
+	}
+}
+
+func TestValidateSchema_InvalidJSON(t *testing.T) {
+	if _, err := validateSchema([]byte(`{"servers": [`)); err == nil {
+		t.Error("expected error for invalid JSON, got nil")
+	}
+}
+
+func TestValidateAllowListFile_SchemaErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	document := `{"servers": [{"name": "io.github.test/server", "owner": "team-test", "description": "Test", "version": "1.0.0",
+		"remotes": [{"type": "sse", "url": "not a url"}]}]}`
+	if err := os.WriteFile(path, []byte(document), 0600); err != nil {
+		t.Fatalf("failed to write allowlist: %v", err)
+	}
+
+	_, err := validateAllowListFile(path, testConfig())
+	if err == nil {
+		t.Fatal("expected schema error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "/servers/0/remotes/0/url") {
+		t.Errorf("expected error to contain JSON pointer, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// environmentFromCluster maps a NAIS cluster name such as "dev-gcp" to the
+// environment it belongs to, or "" if it is not a known environment.
+func environmentFromCluster(cluster string) string {
+	environment, _, _ := strings.Cut(cluster, "-")
+	if !slices.Contains(knownEnvironments, environment) {
+		return ""
+	}
+	return environment
+}
+
+// inScope reports whether a server is served by a registry in environment
+// for audience. An empty environment or audience serves every server, which
+// is what running locally needs. Servers without scoping are served
+// everywhere, and internal servers are hidden from an external registry.
+func inScope(server *StaticServerData, environment, audience string) bool {
+	if environment != "" && server.Environments != nil && !slices.Contains(server.Environments, environment) {
+		return false
+	}
+	if audience == AudienceExternal && server.Audience == AudienceInternal {
+		return false
+	}
+	return true
+}
+
+// scopeServers returns data with only the servers in scope for the
+// configured environment and audience. data is returned as is when every
+// server is in scope.
+func scopeServers(data *StaticRegistryData, config *Config) *StaticRegistryData {
+	if config.Environment == "" && config.Audience == "" {
+		return data
+	}
+
+	scoped := &StaticRegistryData{Mirror: data.Mirror}
+	for i := range data.Servers {
+		if inScope(&data.Servers[i], config.Environment, config.Audience) {
+			scoped.Servers = append(scoped.Servers, data.Servers[i])
+		}
+	}
+	return scoped
+}
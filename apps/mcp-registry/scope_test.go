@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const scopeTestAllowlist = `{"servers": [
+	{"name": "io.github.test/everywhere", "owner": "team-test", "description": "Everywhere", "version": "1.0.0"},
+	{"name": "io.github.test/experimental", "owner": "team-test", "description": "Experimental", "version": "1.0.0", "environments": ["dev"]},
+	{"name": "io.github.test/stable", "owner": "team-test", "description": "Stable", "version": "1.0.0", "environments": ["dev", "prod"]},
+	{"name": "io.github.test/internal", "owner": "team-test", "description": "Internal", "version": "1.0.0", "audience": "internal"},
+	{"name": "io.github.test/external", "owner": "team-test", "description": "External", "version": "1.0.0", "audience": "external", "environments": ["prod"]}
+]}`
+
+func TestEnvironmentFromCluster(t *testing.T) {
+	tests := map[string]string{
+		"dev-gcp":  EnvironmentDev,
+		"prod-gcp": EnvironmentProd,
+		"prod-fss": EnvironmentProd,
+		"dev":      EnvironmentDev,
+		"test-gcp": "",
+		"":         "",
+	}
+
+	for cluster, expected := range tests {
+		if environment := environmentFromCluster(cluster); environment != expected {
+			t.Errorf("cluster %q: expected environment %q, got %q", cluster, expected, environment)
+		}
+	}
+}
+
+func TestRegistry_Scope(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		audience    string
+		expected    []string
+	}{
+		{"unscoped", "", "", []string{"everywhere", "experimental", "external", "internal", "stable"}},
+		{"dev", EnvironmentDev, "", []string{"everywhere", "experimental", "internal", "stable"}},
+		{"prod", EnvironmentProd, "", []string{"everywhere", "external", "internal", "stable"}},
+		{"prod internal", EnvironmentProd, AudienceInternal, []string{"everywhere", "external", "internal", "stable"}},
+		{"prod external", EnvironmentProd, AudienceExternal, []string{"everywhere", "external", "stable"}},
+		{"unknown environment", "staging", "", []string{"everywhere", "internal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "allowlist.json")
+			writeAllowlist(t, path, scopeTestAllowlist, time.Now())
+
+			config := testConfig()
+			config.Environment = tt.environment
+			config.Audience = tt.audience
+			registry := NewRegistry(newFileStore(path, config), config, nil)
+			if err := registry.Load(); err != nil {
+				t.Fatalf("failed to load allowlist: %v", err)
+			}
+
+			var names []string
+			for _, s := range registry.Servers() {
+				names = append(names, s.Server.Name[len("io.github.test/"):])
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("expected servers %v, got %v", tt.expected, names)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("expected servers %v, got %v", tt.expected, names)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_ScopeInMeta(t *testing.T) {
+	registry := loadTestRegistry(t, scopeTestAllowlist, time.Now())
+
+	stable, _ := registry.Find("io.github.test/stable", VersionLatest)
+	if stable.Meta.Nav == nil || len(stable.Meta.Nav.Environments) != 2 {
+		t.Errorf("expected environments in _meta, got %+v", stable.Meta.Nav)
+	}
+
+	external, _ := registry.Find("io.github.test/external", VersionLatest)
+	if external.Meta.Nav == nil || external.Meta.Nav.Audience != AudienceExternal {
+		t.Errorf("expected audience in _meta, got %+v", external.Meta.Nav)
+	}
+
+	everywhere, _ := registry.Find("io.github.test/everywhere", VersionLatest)
+	if nav := everywhere.Meta.Nav; nav != nil && (len(nav.Environments) != 0 || nav.Audience != "") {
+		t.Errorf("expected no scope in _meta for an unscoped server, got %+v", nav)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serve serves HTTP on listener until ctx is done, and then shuts down the
+// way Kubernetes expects: the registry is drained so /ready fails, requests
+// are still served for delay while the pod is removed from the load
+// balancer, and in-flight requests then get up to timeout to finish.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, registry *Registry, delay, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down", "delay", delay.String(), "timeout", timeout.String())
+	registry.Drain()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(delay):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("in-flight requests did not finish: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
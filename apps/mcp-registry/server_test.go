@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe_Shutdown(t *testing.T) {
+	registry := testRegistry(t)
+	mux := http.NewServeMux()
+	mux.Handle("GET /ready", makeReadyHandler(registry))
+	started := make(chan struct{})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, &http.Server{Handler: mux}, listener, registry, 100*time.Millisecond, time.Second)
+	}()
+
+	get := func(path string) (int, error) {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if status, err := get("/ready"); err != nil || status != http.StatusOK {
+		t.Fatalf("before shutdown: expected /ready 200, got %d (%v)", status, err)
+	}
+
+	slow := make(chan int, 1)
+	go func() {
+		status, _ := get("/slow")
+		slow <- status
+	}()
+	<-started
+	cancel()
+
+	// Requests are still served during the delay, with /ready failing.
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for {
+		status, err := get("/ready")
+		if err != nil {
+			t.Fatalf("during delay: /ready failed: %v", err)
+		}
+		if status == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("during delay: expected /ready 503, got %d", status)
+		}
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	if status := <-slow; status != http.StatusOK {
+		t.Errorf("expected in-flight request to finish with 200, got %d", status)
+	}
+	if _, err := get("/ready"); err == nil {
+		t.Error("expected connections to be refused after shutdown")
+	}
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	registry := testRegistry(t)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, &http.Server{Handler: handler}, listener, registry, time.Millisecond, 50*time.Millisecond)
+	}()
+	go func() {
+		if resp, err := http.Get("http://" + listener.Addr().String()); err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Error("expected an error when in-flight requests outlast the timeout")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not give up on in-flight requests")
+	}
+}
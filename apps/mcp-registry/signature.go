@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	signatureAlgorithmEd25519 = "ed25519"
+	// signatureAlgorithmECDSA is what cosign generate-key-pair and
+	// cosign sign-blob use: ECDSA over the SHA-256 digest of the file.
+	signatureAlgorithmECDSA = "ecdsa-sha256"
+)
+
+// signedAllowlist is the evidence that an allowlist file was signed: the
+// exact bytes that were verified, their detached signature and the public
+// key that verified it.
+type signedAllowlist struct {
+	Algorithm string
+	PublicKey []byte
+	Signature []byte
+	Document  []byte
+}
+
+// signaturePath returns where the detached signature of the allowlist at
+// path is read from, which is where cosign sign-blob --output-signature
+// conventionally writes it.
+func signaturePath(path string) string {
+	return path + ".sig"
+}
+
+// verifyAllowListSignature checks that document, read from path, matches
+// the detached signature next to it under the PEM public key in keyFile.
+func verifyAllowListSignature(path string, document []byte, keyFile string) (*signedAllowlist, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read public key: %v", err)
+	}
+	key, algorithm, err := parsePublicKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", keyFile, err)
+	}
+
+	sigPath := signaturePath(path)
+	encoded, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist is not signed: %v", err)
+	}
+	signature, err := decodeSignature(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s: %w", sigPath, err)
+	}
+	if err := verifySignature(key, document, signature); err != nil {
+		return nil, fmt.Errorf("signature %s does not match the allowlist: %w", sigPath, err)
+	}
+
+	return &signedAllowlist{Algorithm: algorithm, PublicKey: keyPEM, Signature: signature, Document: document}, nil
+}
+
+// parsePublicKey reads an ed25519 or ECDSA public key from a PKIX "PUBLIC
+// KEY" PEM block, as written by openssl pkey -pubout and cosign.
+func parsePublicKey(data []byte) (any, string, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, "", errors.New("expected a PEM encoded PUBLIC KEY")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, "", err
+	}
+	switch key.(type) {
+	case ed25519.PublicKey:
+		return key, signatureAlgorithmEd25519, nil
+	case *ecdsa.PublicKey:
+		return key, signatureAlgorithmECDSA, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %T, must be ed25519 or ECDSA", key)
+	}
+}
+
+// decodeSignature accepts a raw signature, as written by openssl pkeyutl,
+// or a base64 encoded one, as written by cosign sign-blob.
+func decodeSignature(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("signature is empty")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(trimmed))
+	if err != nil {
+		return data, nil
+	}
+	return decoded, nil
+}
+
+func verifySignature(key any, document, signature []byte) error {
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, document, signature) {
+			return errors.New("ed25519 verification failed")
+		}
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(document)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("ECDSA verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
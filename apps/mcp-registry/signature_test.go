@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedTestAllowlist writes storeTestAllowlist to a temporary directory,
+// signs it with a new ed25519 key and returns a config that requires the
+// signature, the allowlist path and the private key.
+func signedTestAllowlist(t *testing.T) (*Config, string, ed25519.PrivateKey) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "allowlist.json")
+	writeAllowlist(t, path, storeTestAllowlist, time.Now().Add(-time.Hour))
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	config := testConfig()
+	config.PublicKeyFile = filepath.Join(dir, "allowlist.pub")
+	writePublicKey(t, config.PublicKeyFile, public)
+	signFile(t, path, func(data []byte) []byte {
+		return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, data)))
+	})
+	return config, path, private
+}
+
+func writePublicKey(t *testing.T, path string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+}
+
+func signFile(t *testing.T, path string, sign func([]byte) []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if err := os.WriteFile(signaturePath(path), sign(data), 0o600); err != nil {
+		t.Fatalf("failed to write signature: %v", err)
+	}
+}
+
+func TestVerifyAllowListSignature(t *testing.T) {
+	document := []byte(storeTestAllowlist)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	digest := sha256.Sum256(document)
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecPrivate, digest[:])
+
+	tests := []struct {
+		name          string
+		key           any
+		signature     []byte
+		wantAlgorithm string
+		wantErr       string
+	}{
+		{
+			name:          "ed25519 base64",
+			key:           edPublic,
+			signature:     []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edPrivate, document)) + "\n"),
+			wantAlgorithm: signatureAlgorithmEd25519,
+		},
+		{
+			name:          "ed25519 raw",
+			key:           edPublic,
+			signature:     ed25519.Sign(edPrivate, document),
+			wantAlgorithm: signatureAlgorithmEd25519,
+		},
+		{
+			name:          "cosign ECDSA",
+			key:           &ecPrivate.PublicKey,
+			signature:     []byte(base64.StdEncoding.EncodeToString(ecSignature)),
+			wantAlgorithm: signatureAlgorithmECDSA,
+		},
+		{
+			name:      "signature of other content",
+			key:       edPublic,
+			signature: []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edPrivate, []byte("{}")))),
+			wantErr:   "does not match",
+		},
+		{
+			name:      "empty signature",
+			key:       edPublic,
+			signature: []byte("\n"),
+			wantErr:   "signature is empty",
+		},
+		{
+			name:      "unsupported key",
+			key:       &rsaPrivate.PublicKey,
+			signature: []byte("c2lnbmF0dXJl"),
+			wantErr:   "unsupported key type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "allowlist.json")
+			keyFile := filepath.Join(dir, "allowlist.pub")
+			writePublicKey(t, keyFile, tt.key)
+			if err := os.WriteFile(signaturePath(path), tt.signature, 0o600); err != nil {
+				t.Fatalf("failed to write signature: %v", err)
+			}
+
+			signed, err := verifyAllowListSignature(path, document, keyFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if signed.Algorithm != tt.wantAlgorithm {
+				t.Errorf("expected algorithm %s, got %s", tt.wantAlgorithm, signed.Algorithm)
+			}
+			if string(signed.Document) != string(document) {
+				t.Error("expected the verified document to be kept")
+			}
+		})
+	}
+}
+
+func TestValidateAllowListFile_Signature(t *testing.T) {
+	config, path, _ := signedTestAllowlist(t)
+
+	data, err := validateAllowListFile(path, config)
+	if err != nil {
+		t.Fatalf("expected signed allowlist to be valid, got %v", err)
+	}
+	if data.signed == nil {
+		t.Error("expected the signature to be kept with the allowlist")
+	}
+
+	tampered := strings.Replace(storeTestAllowlist, "Existing server", "Tampered server", 1)
+	writeAllowlist(t, path, tampered, time.Now())
+	if _, err := validateAllowListFile(path, config); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected tampered allowlist to be rejected, got %v", err)
+	}
+
+	if err := os.Remove(signaturePath(path)); err != nil {
+		t.Fatalf("failed to remove signature: %v", err)
+	}
+	if _, err := validateAllowListFile(path, config); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("expected unsigned allowlist to be rejected, got %v", err)
+	}
+}
+
+func TestFileStore_Signed(t *testing.T) {
+	config, path, private := signedTestAllowlist(t)
+	store := newFileStore(path, config)
+	ctx := context.Background()
+	if _, _, err := store.List(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.SetStatus(ctx, "io.github.navikt/existing", "1.0.0", statusChange{Status: StatusDeprecated}); !errors.Is(err, errSignedAllowlist) {
+		t.Errorf("expected errSignedAllowlist, got %v", err)
+	}
+
+	// Re-signing is a change even if the allowlist itself is untouched.
+	signFile(t, path, func(data []byte) []byte { return ed25519.Sign(private, data) })
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(signaturePath(path), later, later); err != nil {
+		t.Fatalf("failed to touch signature: %v", err)
+	}
+	if changed, err := store.Changed(ctx); err != nil || !changed {
+		t.Errorf("expected a new signature to be a change, got %v, %v", changed, err)
+	}
+}
+
+func TestSignatureHandler(t *testing.T) {
+	t.Run("unsigned", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		serveTestRequest(w, httptest.NewRequest(http.MethodGet, "/v0.1/signature", nil), testRegistry(t))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		config, path, private := signedTestAllowlist(t)
+		registry := NewRegistry(newFileStore(path, config), config, nil)
+		if err := registry.Load(); err != nil {
+			t.Fatalf("failed to load signed allowlist: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		serveTestRequest(w, httptest.NewRequest(http.MethodGet, "/v0.1/signature", nil), registry)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response SignatureResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		key, _, err := parsePublicKey([]byte(response.PublicKey))
+		if err != nil {
+			t.Fatalf("expected a usable public key, got %v", err)
+		}
+		if !key.(ed25519.PublicKey).Equal(private.Public()) {
+			t.Error("expected the configured public key")
+		}
+		if err := verifySignature(key, response.Document, response.Signature); err != nil {
+			t.Errorf("expected the served document to verify offline, got %v", err)
+		}
+		digest := sha256.Sum256([]byte(storeTestAllowlist))
+		if response.Digest != "sha256:"+hex.EncodeToString(digest[:]) {
+			t.Errorf("unexpected digest %s", response.Digest)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	errServerExists     = errors.New("server version already exists")
+	errServerNotFound   = errors.New("server version not found")
+	errInvalidAllowlist = errors.New("change would make the allowlist invalid")
+	errSignedAllowlist  = errors.New("the allowlist is signed and can only be changed by signing a new file")
+)
+
+// RegistryStore holds the locally managed server entries. Entries are
+// stored as written, with template variables in place. Implementations must
+// reject a write that would make the allowlist invalid, and leave the stored
+// entries untouched in that case.
+type RegistryStore interface {
+	// List returns every entry, validated and with template variables
+	// substituted, and the time the entries last changed.
+	List(ctx context.Context) (*StaticRegistryData, time.Time, error)
+	// GetVersion returns a single entry as stored. It returns
+	// errServerNotFound if the name and version are not in the store.
+	GetVersion(ctx context.Context, name, version string) (StaticServerData, error)
+	// Put adds a new server version. It returns errServerExists if the name
+	// and version are already in the store.
+	Put(ctx context.Context, server StaticServerData) error
+	// SetStatus changes the status of a server version and the deprecation
+	// details that go with it. It returns errServerNotFound if the name and
+	// version are not in the store.
+	SetStatus(ctx context.Context, name, version string, change statusChange) error
+	// Changed reports whether the entries may have changed since the last
+	// call to List, including by another replica or a manual edit.
+	Changed(ctx context.Context) (bool, error)
+	// String describes where the entries are stored, for logging.
+	String() string
+}
+
+// fileStore keeps entries in the allowlist file. Every write goes to a
+// temporary file in the same directory, is validated, and is renamed over
+// the allowlist, so readers never see a partial or invalid file.
+type fileStore struct {
+	path   string
+	config *Config
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	// sigModTime is the modification time of the signature, so re-signing
+	// a rejected edit is picked up as a change.
+	sigModTime time.Time
+}
+
+func newFileStore(path string, config *Config) *fileStore {
+	return &fileStore{path: path, config: config}
+}
+
+func (s *fileStore) String() string {
+	return s.path
+}
+
+// rawAllowlist keeps servers as raw JSON, so entries that a change does not
+// touch are written back as they were, template variables included.
+type rawAllowlist struct {
+	Servers []json.RawMessage `json:"servers"`
+	Mirror  json.RawMessage   `json:"mirror,omitempty"`
+}
+
+func (s *fileStore) List(_ context.Context) (*StaticRegistryData, time.Time, error) {
+	fileInfo, err := os.Stat(s.path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("cannot stat %s: %v", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.modTime = fileInfo.ModTime()
+	s.size = fileInfo.Size()
+	s.sigModTime = s.signatureModTime()
+	s.mu.Unlock()
+
+	data, err := validateAllowListFile(s.path, s.config)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, fileInfo.ModTime().UTC(), nil
+}
+
+func (s *fileStore) Changed(_ context.Context) (bool, error) {
+	fileInfo, err := os.Stat(s.path)
+	if err != nil {
+		return false, fmt.Errorf("cannot stat %s: %v", s.path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !fileInfo.ModTime().Equal(s.modTime) || fileInfo.Size() != s.size || !s.signatureModTime().Equal(s.sigModTime), nil
+}
+
+// signatureModTime returns when the signature of a signed allowlist last
+// changed, or the zero time if it is unsigned or the signature is missing.
+func (s *fileStore) signatureModTime() time.Time {
+	if s.config.PublicKeyFile == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(signaturePath(s.path))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func (s *fileStore) GetVersion(_ context.Context, name, version string) (StaticServerData, error) {
+	allowlist, err := s.read()
+	if err != nil {
+		return StaticServerData{}, err
+	}
+	_, server, err := findRawServer(allowlist, name, version)
+	return server, err
+}
+
+func (s *fileStore) Put(_ context.Context, server StaticServerData) error {
+	return s.update(func(allowlist *rawAllowlist) error {
+		if _, _, err := findRawServer(allowlist, server.Name, server.Version); err == nil {
+			return errServerExists
+		}
+		entry, err := json.Marshal(server)
+		if err != nil {
+			return err
+		}
+		allowlist.Servers = append(allowlist.Servers, entry)
+		return nil
+	})
+}
+
+func (s *fileStore) SetStatus(_ context.Context, name, version string, change statusChange) error {
+	return s.update(func(allowlist *rawAllowlist) error {
+		i, server, err := findRawServer(allowlist, name, version)
+		if err != nil {
+			return err
+		}
+		change.apply(&server)
+		entry, err := json.Marshal(server)
+		if err != nil {
+			return err
+		}
+		allowlist.Servers[i] = entry
+		return nil
+	})
+}
+
+// statusChange is a new status for a server version, with the reason it was
+// deprecated or deleted and the server that replaces it.
+type statusChange struct {
+	Status             string `json:"status"`
+	DeprecationMessage string `json:"deprecationMessage,omitempty"`
+	ReplacedBy         string `json:"replacedBy,omitempty"`
+}
+
+// apply sets the status of server. The deprecation details are replaced as a
+// whole, so reactivating a version clears them.
+func (c statusChange) apply(server *StaticServerData) {
+	server.Status = c.Status
+	server.DeprecationMessage = c.DeprecationMessage
+	server.ReplacedBy = c.ReplacedBy
+}
+
+func findRawServer(allowlist *rawAllowlist, name, version string) (int, StaticServerData, error) {
+	for i, entry := range allowlist.Servers {
+		var server StaticServerData
+		if err := json.Unmarshal(entry, &server); err != nil {
+			return 0, StaticServerData{}, fmt.Errorf("server[%d]: %v", i, err)
+		}
+		if server.Name == name && server.Version == version {
+			return i, server, nil
+		}
+	}
+	return 0, StaticServerData{}, errServerNotFound
+}
+
+func (s *fileStore) read() (*rawAllowlist, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %v", s.path, err)
+	}
+	var allowlist rawAllowlist
+	if err := json.Unmarshal(data, &allowlist); err != nil {
+		return nil, fmt.Errorf("invalid JSON format: %v", err)
+	}
+	return &allowlist, nil
+}
+
+// update applies change to the allowlist file. Writes are serialized, so
+// concurrent changes cannot overwrite each other. A signed allowlist is
+// never changed, since the registry could not verify the result.
+func (s *fileStore) update(change func(*rawAllowlist) error) error {
+	if s.config.PublicKeyFile != "" {
+		return errSignedAllowlist
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	allowlist, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if err := change(allowlist); err != nil {
+		return err
+	}
+
+	updated, err := json.MarshalIndent(allowlist, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.replace(append(updated, '\n'))
+}
+
+// replace validates data as an allowlist and atomically renames it over
+// the allowlist file.
+func (s *fileStore) replace(data []byte) error {
+	if report := checkAllowList(data, s.config); len(report.errors) > 0 {
+		return fmt.Errorf("%w: %w", errInvalidAllowlist, errors.Join(report.errors...))
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("cannot stat %s: %v", s.path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+"-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %v", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write temporary file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot sync temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write temporary file: %v", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("cannot set permissions on temporary file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("cannot replace %s: %v", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const storeTestAllowlist = `{
+  "servers": [
+    {
+      "name": "io.github.navikt/existing",
+      "description": "Existing server",
+      "owner": "team-test",
+      "version": "1.0.0",
+      "publishedAt": "2025-01-01T00:00:00Z",
+      "remotes": [{"type": "streamable-http", "url": "https://existing.{{domain_internal}}/mcp"}]
+    }
+  ]
+}`
+
+// testRegistryStore checks the behaviour every RegistryStore must have. The
+// store must hold the entries of storeTestAllowlist.
+func testRegistryStore(t *testing.T, store RegistryStore) {
+	ctx := context.Background()
+
+	data, _, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	if len(data.Servers) != 1 || data.Servers[0].Remotes[0].URL != "https://existing.intern.dev.nav.no/mcp" {
+		t.Fatalf("List: expected the existing server with substituted url, got %+v", data.Servers)
+	}
+	if changed, err := store.Changed(ctx); err != nil || changed {
+		t.Errorf("Changed: expected no change right after List, got %v (%v)", changed, err)
+	}
+
+	stored, err := store.GetVersion(ctx, "io.github.navikt/existing", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetVersion: unexpected error: %v", err)
+	}
+	if stored.Remotes[0].URL != "https://existing.{{domain_internal}}/mcp" {
+		t.Errorf("GetVersion: expected entry as stored, got url %s", stored.Remotes[0].URL)
+	}
+	if _, err := store.GetVersion(ctx, "io.github.navikt/existing", "2.0.0"); !errors.Is(err, errServerNotFound) {
+		t.Errorf("GetVersion: expected errServerNotFound, got %v", err)
+	}
+
+	server := StaticServerData{
+		Name:        "io.github.navikt/new-server",
+		Description: "New server",
+		Version:     "1.0.0",
+		Owner:       "team-test",
+		Remotes:     []Transport{{Type: TransportTypeSSE, URL: "https://new.{{domain_external}}/sse"}},
+	}
+	if err := store.Put(ctx, server); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if changed, err := store.Changed(ctx); err != nil || !changed {
+		t.Errorf("Changed: expected change after Put, got %v (%v)", changed, err)
+	}
+	if err := store.Put(ctx, server); !errors.Is(err, errServerExists) {
+		t.Errorf("Put: expected errServerExists for a duplicate, got %v", err)
+	}
+
+	invalid := server
+	invalid.Version = "2.0.0"
+	invalid.Remotes = []Transport{{Type: TransportTypeSSE, URL: "https://new.{{unknown}}/sse"}}
+	if err := store.Put(ctx, invalid); !errors.Is(err, errInvalidAllowlist) {
+		t.Errorf("Put: expected errInvalidAllowlist, got %v", err)
+	}
+
+	if err := store.SetStatus(ctx, "io.github.navikt/existing", "1.0.0", statusChange{Status: StatusDeprecated}); err != nil {
+		t.Fatalf("SetStatus: unexpected error: %v", err)
+	}
+	deprecation := statusChange{Status: StatusDeprecated, DeprecationMessage: "Use the new server", ReplacedBy: "io.github.navikt/new-server"}
+	if err := store.SetStatus(ctx, "io.github.navikt/existing", "1.0.0", deprecation); err != nil {
+		t.Fatalf("SetStatus: unexpected error: %v", err)
+	}
+	if err := store.SetStatus(ctx, "io.github.navikt/existing", "1.0.0", statusChange{Status: StatusActive, DeprecationMessage: "Active"}); !errors.Is(err, errInvalidAllowlist) {
+		t.Errorf("SetStatus: expected errInvalidAllowlist for a deprecation message on an active version, got %v", err)
+	}
+	if err := store.SetStatus(ctx, "io.github.navikt/existing", "1.0.0", statusChange{Status: "retired"}); !errors.Is(err, errInvalidAllowlist) {
+		t.Errorf("SetStatus: expected errInvalidAllowlist for an unknown status, got %v", err)
+	}
+	if err := store.SetStatus(ctx, "io.github.navikt/missing", "1.0.0", statusChange{Status: StatusDeprecated}); !errors.Is(err, errServerNotFound) {
+		t.Errorf("SetStatus: expected errServerNotFound, got %v", err)
+	}
+
+	data, updatedAt, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	if len(data.Servers) != 2 {
+		t.Fatalf("List: expected 2 servers, got %+v", data.Servers)
+	}
+	if updatedAt.IsZero() {
+		t.Error("List: expected an update time")
+	}
+	for _, s := range data.Servers {
+		if s.Name == "io.github.navikt/existing" && (s.Status != StatusDeprecated || s.ReplacedBy != "io.github.navikt/new-server") {
+			t.Errorf("List: expected existing server to be deprecated and replaced, got %q replaced by %q", s.Status, s.ReplacedBy)
+		}
+		if s.Name == "io.github.navikt/new-server" && s.Remotes[0].URL != "https://new.ekstern.dev.nav.no/sse" {
+			t.Errorf("List: expected new server with substituted url, got %s", s.Remotes[0].URL)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, storeTestAllowlist, time.Now().Add(-time.Hour))
+
+	testRegistryStore(t, newFileStore(path, testConfig()))
+}
+
+func TestFileStore_KeepsFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	writeAllowlist(t, path, storeTestAllowlist, time.Now().Add(-time.Hour))
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("failed to chmod allowlist: %v", err)
+	}
+
+	store := newFileStore(path, testConfig())
+	if err := store.SetStatus(context.Background(), "io.github.navikt/existing", "1.0.0", statusChange{Status: StatusDeprecated}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat allowlist: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("expected mode 0640 to be kept, got %v", info.Mode().Perm())
+	}
+}
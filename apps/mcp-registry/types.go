@@ -9,19 +9,124 @@ const (
 	TransportTypeSSE            = "sse"
 	TransportTypeStdio          = "stdio"
 
+	RegistryTypeNPM   = "npm"
+	RegistryTypePyPI  = "pypi"
+	RegistryTypeOCI   = "oci"
+	RegistryTypeNuGet = "nuget"
+	RegistryTypeMCPB  = "mcpb"
+
+	ArgumentTypePositional = "positional"
+	ArgumentTypeNamed      = "named"
+
+	InputFormatString   = "string"
+	InputFormatNumber   = "number"
+	InputFormatBoolean  = "boolean"
+	InputFormatFilepath = "filepath"
+
 	StatusActive     = "active"
 	StatusDeprecated = "deprecated"
 	StatusDeleted    = "deleted"
 
-	NameMinLength        = 3
-	NameMaxLength        = 200
-	DescriptionMinLength = 1
-	DescriptionMaxLength = 100
+	VersionLatest = "latest"
+
+	EnvironmentDev  = "dev"
+	EnvironmentProd = "prod"
+
+	AudienceInternal = "internal"
+	AudienceExternal = "external"
+
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+
+	ClassificationPublic               = "public"
+	ClassificationInternal             = "internal"
+	ClassificationConfidential         = "confidential"
+	ClassificationStrictlyConfidential = "strictly-confidential"
+
+	HealthReachable    = "reachable"
+	HealthAuthRequired = "auth_required"
+	HealthUnreachable  = "unreachable"
+
+	ClientVSCode     = "vscode"
+	ClientCopilotCLI = "copilot-cli"
+	ClientGeneric    = "generic"
+
+	// NavMetaKey namespaces Nav's extensions in _meta, next to the official
+	// registry extensions.
+	NavMetaKey = "no.nav/mcp-registry"
+
+	// OwnerTeamURL is where the GitHub team owning a server is, followed by
+	// its slug.
+	OwnerTeamURL = "https://github.com/orgs/navikt/teams/"
+
+	NameMinLength               = 3
+	NameMaxLength               = 200
+	DescriptionMinLength        = 1
+	DescriptionMaxLength        = 100
+	DeprecationMessageMaxLength = 500
 )
 
+var (
+	knownEnvironments = []string{EnvironmentDev, EnvironmentProd}
+	knownAudiences    = []string{AudienceInternal, AudienceExternal}
+	knownClients      = []string{ClientVSCode, ClientCopilotCLI, ClientGeneric}
+	knownRisks        = []string{RiskLow, RiskMedium, RiskHigh}
+	// knownClassifications are Nav's data classes: åpen, intern, fortrolig
+	// and strengt fortrolig.
+	knownClassifications = []string{ClassificationPublic, ClassificationInternal, ClassificationConfidential, ClassificationStrictlyConfidential}
+)
+
+// Transport is how a client connects to a server. Headers are sent with
+// every request, and Variables define the {name} placeholders in the URL of
+// a remote.
 type Transport struct {
-	Type string `json:"type"`
-	URL  string `json:"url,omitempty"`
+	Type      string           `json:"type"`
+	URL       string           `json:"url,omitempty"`
+	Headers   []KeyValueInput  `json:"headers,omitempty"`
+	Variables map[string]Input `json:"variables,omitempty"`
+}
+
+// Input describes a value a client may need to supply when configuring a
+// server, such as an argument or an environment variable.
+type Input struct {
+	Description string   `json:"description,omitempty"`
+	IsRequired  bool     `json:"isRequired,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Value       string   `json:"value,omitempty"`
+	IsSecret    bool     `json:"isSecret,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Placeholder string   `json:"placeholder,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+}
+
+type KeyValueInput struct {
+	Name string `json:"name"`
+	Input
+	Variables map[string]Input `json:"variables,omitempty"`
+}
+
+type Argument struct {
+	Type       string `json:"type"`
+	Name       string `json:"name,omitempty"`
+	ValueHint  string `json:"valueHint,omitempty"`
+	IsRepeated bool   `json:"isRepeated,omitempty"`
+	Input
+	Variables map[string]Input `json:"variables,omitempty"`
+}
+
+// Package describes how to download and run a server locally.
+type Package struct {
+	RegistryType         string          `json:"registryType"`
+	RegistryBaseURL      string          `json:"registryBaseUrl,omitempty"`
+	Identifier           string          `json:"identifier"`
+	Version              string          `json:"version,omitempty"`
+	FileSHA256           string          `json:"fileSha256,omitempty"`
+	RuntimeHint          string          `json:"runtimeHint,omitempty"`
+	Transport            Transport       `json:"transport"`
+	RuntimeArguments     []Argument      `json:"runtimeArguments,omitempty"`
+	PackageArguments     []Argument      `json:"packageArguments,omitempty"`
+	EnvironmentVariables []KeyValueInput `json:"environmentVariables,omitempty"`
 }
 
 type ServerJSON struct {
@@ -29,9 +134,19 @@ type ServerJSON struct {
 	Name        string      `json:"name"`
 	Description string      `json:"description"`
 	Version     string      `json:"version"`
+	Repository  *Repository `json:"repository,omitempty"`
+	Packages    []Package   `json:"packages,omitempty"`
 	Remotes     []Transport `json:"remotes,omitempty"`
 }
 
+// Repository is where the source code of a server is, as in server.json.
+type Repository struct {
+	URL       string `json:"url"`
+	Source    string `json:"source"`
+	ID        string `json:"id,omitempty"`
+	Subfolder string `json:"subfolder,omitempty"`
+}
+
 type RegistryExtensions struct {
 	Status      string    `json:"status"`
 	PublishedAt time.Time `json:"publishedAt"`
@@ -39,8 +154,62 @@ type RegistryExtensions struct {
 	IsLatest    bool      `json:"isLatest"`
 }
 
+// NavExtensions holds the registry metadata that is specific to Nav.
+type NavExtensions struct {
+	Deprecation  *Deprecation   `json:"deprecation,omitempty"`
+	Health       []RemoteHealth `json:"health,omitempty"`
+	Environments []string       `json:"environments,omitempty"`
+	Audience     string         `json:"audience,omitempty"`
+	Ownership    *Ownership     `json:"ownership,omitempty"`
+	Review       *Review        `json:"review,omitempty"`
+}
+
+// Review is the security review of a server: how risky it is, the most
+// sensitive data it may access, when and by whom it was reviewed, and the
+// scopes or permissions it needs. ReviewedAt is a date, such as
+// "2025-06-01".
+type Review struct {
+	Risk               string   `json:"risk"`
+	DataClassification string   `json:"dataClassification,omitempty"`
+	ReviewedAt         string   `json:"reviewedAt,omitempty"`
+	Reviewer           string   `json:"reviewer,omitempty"`
+	Scopes             []string `json:"scopes,omitempty"`
+}
+
+// Ownership tells who to contact about a server. Owner is the GitHub team
+// responsible for the entry.
+type Ownership struct {
+	Owner         string `json:"owner,omitempty"`
+	OwnerURL      string `json:"ownerUrl,omitempty"`
+	Contact       string `json:"contact,omitempty"`
+	Repository    string `json:"repository,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Deprecation tells clients why a version is deprecated and what to use
+// instead.
+type Deprecation struct {
+	Message    string `json:"message"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
+}
+
+// RemoteHealth is the result of the last probe of a remote. A remote that
+// requires authentication is up, but its protocol version is unknown.
+// LatencyMs and CheckedAt change on every probe, so they are left out of the
+// JSON, and with it the ETag, and only reach the metrics and the catalog.
+type RemoteHealth struct {
+	URL             string    `json:"url"`
+	Status          string    `json:"status"`
+	LatencyMs       int64     `json:"-"`
+	ProtocolVersion string    `json:"protocolVersion,omitempty"`
+	OAuthMetadata   bool      `json:"oauthMetadata"`
+	Error           string    `json:"error,omitempty"`
+	CheckedAt       time.Time `json:"-"`
+}
+
 type ResponseMeta struct {
 	Official *RegistryExtensions `json:"io.modelcontextprotocol.registry/official,omitempty"`
+	Nav      *NavExtensions      `json:"no.nav/mcp-registry,omitempty"`
 }
 
 type ServerResponse struct {
@@ -58,6 +227,27 @@ type ServerListResponse struct {
 	Metadata Metadata         `json:"metadata"`
 }
 
+// ClientConfigResponse is the configuration of a server for an MCP client,
+// ready to paste into the client's config file.
+type ClientConfigResponse struct {
+	Client             string `json:"client"`
+	Name               string `json:"name"`
+	Config             any    `json:"config"`
+	InstallURL         string `json:"installUrl,omitempty"`
+	InsidersInstallURL string `json:"insidersInstallUrl,omitempty"`
+}
+
+// SignatureResponse lets auditors verify offline that the served list was
+// built from a signed allowlist file. Document holds the exact bytes that
+// were signed, and Signature and Document are base64 encoded.
+type SignatureResponse struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+	Signature []byte `json:"signature"`
+	Digest    string `json:"digest"`
+	Document  []byte `json:"document"`
+}
+
 type StaticServerData struct {
 	Schema      string      `json:"$schema,omitempty"`
 	Name        string      `json:"name"`
@@ -65,9 +255,44 @@ type StaticServerData struct {
 	Version     string      `json:"version"`
 	Status      string      `json:"status,omitempty"`
 	PublishedAt string      `json:"publishedAt,omitempty"`
+	Packages    []Package   `json:"packages,omitempty"`
 	Remotes     []Transport `json:"remotes,omitempty"`
+	// DeprecationMessage and ReplacedBy explain a deprecated or deleted
+	// version. ReplacedBy is the name of the server to use instead.
+	DeprecationMessage string `json:"deprecationMessage,omitempty"`
+	ReplacedBy         string `json:"replacedBy,omitempty"`
+	// Environments and Audience scope where a server is served. A server
+	// without them is served everywhere.
+	Environments []string `json:"environments,omitempty"`
+	Audience     string   `json:"audience,omitempty"`
+	// Owner is the slug of the GitHub team responsible for the entry, and
+	// Contact the Slack channel or email address to reach it on.
+	Owner         string      `json:"owner,omitempty"`
+	Contact       string      `json:"contact,omitempty"`
+	Repository    *Repository `json:"repository,omitempty"`
+	Documentation string      `json:"documentation,omitempty"`
+	Review        *Review     `json:"review,omitempty"`
 }
 
 type StaticRegistryData struct {
 	Servers []StaticServerData `json:"servers"`
+	Mirror  *MirrorConfig      `json:"mirror,omitempty"`
+
+	// signed is set when the file this was read from has a verified
+	// signature.
+	signed *signedAllowlist
+}
+
+// MirrorConfig selects servers to mirror from the upstream registry. Include
+// holds exact names or path.Match patterns, such as "com.atlassian/*".
+type MirrorConfig struct {
+	Include   []string                  `json:"include"`
+	Overrides map[string]MirrorOverride `json:"overrides,omitempty"`
+}
+
+// MirrorOverride replaces fields of every mirrored version of a server.
+type MirrorOverride struct {
+	Status      string      `json:"status,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Remotes     []Transport `json:"remotes,omitempty"`
 }
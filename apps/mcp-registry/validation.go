@@ -2,83 +2,223 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/mail"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
 
 var serverNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.-]*/[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
 
-func validateAllowListFile() error {
-	data, err := os.ReadFile("allowlist.json")
+func validateAllowListFile(path string, config *Config) (*StaticRegistryData, error) {
+	return reportResult(checkAllowListFile(path, config), path)
+}
+
+// reportResult returns the allowlist of a report without errors, and logs
+// its warnings with source as the allowlist's origin.
+func reportResult(report allowListReport, source string) (*StaticRegistryData, error) {
+	if len(report.errors) > 0 {
+		return nil, errors.Join(report.errors...)
+	}
+
+	for _, warning := range report.warnings {
+		slog.Warn("Allowlist validation warning", "file", source, "warning", warning.Error())
+	}
+
+	return report.data, nil
+}
+
+// allowListReport holds every error and warning found in an allowlist file.
+type allowListReport struct {
+	data     *StaticRegistryData
+	errors   []error
+	warnings []error
+}
+
+// checkAllowListFile runs every check on the allowlist at path and collects
+// all problems instead of stopping at the first one. When a public key is
+// configured, the file must match its detached signature.
+func checkAllowListFile(path string, config *Config) allowListReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return allowListReport{errors: []error{fmt.Errorf("cannot read %s: %v", path, err)}}
+	}
+	if config.PublicKeyFile == "" {
+		return checkAllowList(data, config)
+	}
+
+	signed, err := verifyAllowListSignature(path, data, config.PublicKeyFile)
+	report := checkAllowList(data, config)
 	if err != nil {
-		return fmt.Errorf("cannot read allowlist.json: %v", err)
+		report.errors = append([]error{err}, report.errors...)
+	} else if report.data != nil {
+		report.data.signed = signed
+	}
+	return report
+}
+
+// checkAllowList runs every check on an allowlist document, wherever it is
+// stored.
+func checkAllowList(data []byte, config *Config) allowListReport {
+	var report allowListReport
+
+	violations, err := validateSchema(data)
+	if err != nil {
+		report.errors = append(report.errors, err)
+		return report
+	}
+	for _, v := range violations {
+		report.errors = append(report.errors, v)
 	}
 
 	var staticData StaticRegistryData
 	if err := json.Unmarshal(data, &staticData); err != nil {
-		return fmt.Errorf("invalid JSON format: %v", err)
+		report.errors = append(report.errors, fmt.Errorf("invalid JSON format: %v", err))
+		return report
 	}
 
-	return validateRegistry(&staticData)
+	variables := config.templateVariables()
+	used := make(map[string]bool)
+	report.errors = append(report.errors, substituteServerVariables(&staticData, variables, used)...)
+	report.errors = append(report.errors, splitErrors(validateRegistry(&staticData))...)
+	for i := range staticData.Servers {
+		if err := requireOwner(&staticData.Servers[i], i); err != nil {
+			report.errors = append(report.errors, err)
+		}
+	}
+	report.warnings = registryWarnings(&staticData)
+	report.warnings = append(report.warnings, reviewWarnings(&staticData, config.ReviewMaxAgeMonths, time.Now())...)
+	for _, name := range slices.Sorted(maps.Keys(config.Variables)) {
+		if !used[name] {
+			report.warnings = append(report.warnings, fmt.Errorf("template variable {{%s}} is configured but not used by any server", name))
+		}
+	}
+	report.data = &staticData
+	return report
+}
+
+// splitErrors flattens errors combined with errors.Join into a list.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, splitErrors(e)...)
+	}
+	return errs
 }
 
+// validateRegistry checks every server entry and returns all problems found,
+// combined with errors.Join.
 func validateRegistry(data *StaticRegistryData) error {
 	if len(data.Servers) == 0 {
 		return fmt.Errorf("registry must contain at least one server")
 	}
 
-	serverNames := make(map[string]bool)
+	var errs []error
+	serverVersions := make(map[serverKey]bool)
 
 	for i := range data.Servers {
-		if err := validateServerEntry(&data.Servers[i], i, serverNames); err != nil {
-			return err
-		}
-		serverNames[data.Servers[i].Name] = true
+		errs = append(errs, validateServerEntry(&data.Servers[i], i, serverVersions))
+		serverVersions[serverKey{data.Servers[i].Name, data.Servers[i].Version}] = true
 	}
 
-	return nil
+	errs = append(errs, validateMirror(data.Mirror))
+
+	return errors.Join(errs...)
 }
 
-func validateServerEntry(server *StaticServerData, index int, existingNames map[string]bool) error {
-	if err := validateName(server.Name, index); err != nil {
-		return err
+// registryWarnings reports problems that do not stop the registry from
+// serving, but that contributors should fix.
+func registryWarnings(data *StaticRegistryData) []error {
+	var warnings []error
+
+	names := make(map[string]bool, len(data.Servers))
+	for i := range data.Servers {
+		names[data.Servers[i].Name] = true
+	}
+
+	for i := range data.Servers {
+		server := &data.Servers[i]
+		if server.PublishedAt == "" {
+			warnings = append(warnings, fmt.Errorf("server[%d]: 'publishedAt' is missing, the allowlist modification time will be used", i))
+		}
+		if server.Status == StatusDeprecated && server.DeprecationMessage == "" {
+			warnings = append(warnings, fmt.Errorf("server[%d]: deprecated without a 'deprecationMessage', clients will not be told why", i))
+		}
+		if server.ReplacedBy != "" && !names[server.ReplacedBy] && !data.Mirror.includes(server.ReplacedBy) {
+			warnings = append(warnings, fmt.Errorf("server[%d]: 'replacedBy' refers to '%s', which is not in the allowlist", i, server.ReplacedBy))
+		}
 	}
 
-	if existingNames[server.Name] {
-		return fmt.Errorf("server[%d]: duplicate server name '%s'", index, server.Name)
+	if data.Mirror != nil {
+		for _, name := range slices.Sorted(maps.Keys(data.Mirror.Overrides)) {
+			if !data.Mirror.includes(name) {
+				warnings = append(warnings, fmt.Errorf("mirror.overrides['%s']: server is not matched by any 'include' pattern", name))
+			}
+		}
 	}
 
-	if err := validateDescription(server.Description, index); err != nil {
-		return err
+	return warnings
+}
+
+// serverKey identifies a single version of a server.
+type serverKey struct {
+	name    string
+	version string
+}
+
+// validateServerEntry checks a single server entry and returns all problems
+// found, combined with errors.Join.
+func validateServerEntry(server *StaticServerData, index int, existing map[serverKey]bool) error {
+	errs := []error{
+		validateName(server.Name, index),
+		validateDescription(server.Description, index),
+		validateVersion(server.Version, index),
 	}
 
-	if strings.TrimSpace(server.Version) == "" {
-		return fmt.Errorf("server[%d]: 'version' is required and cannot be empty", index)
+	if existing[serverKey{server.Name, server.Version}] {
+		errs = append(errs, fmt.Errorf("server[%d]: duplicate server name '%s' with version '%s'", index, server.Name, server.Version))
 	}
 
 	if server.Status != "" {
-		if err := validateStatus(server.Status, index); err != nil {
-			return err
-		}
+		errs = append(errs, validateStatus(server.Status, index))
+	}
+	errs = append(errs, validateDeprecation(server, index))
+	errs = append(errs, validateScope(server, index))
+	errs = append(errs, validateOwnership(server, index))
+	if server.Review != nil {
+		errs = append(errs, validateReview(server.Review, index))
+	}
+
+	for j := range server.Packages {
+		errs = append(errs, validatePackage(&server.Packages[j], index, j))
 	}
 
 	for j := range server.Remotes {
-		if err := validateTransport(&server.Remotes[j], index, j); err != nil {
-			return err
-		}
+		errs = append(errs, validateTransport(&server.Remotes[j], index, j))
 	}
 
 	if server.PublishedAt != "" {
 		if _, err := time.Parse(time.RFC3339, server.PublishedAt); err != nil {
-			return fmt.Errorf("server[%d]: invalid publishedAt format, must be RFC3339: %v", index, err)
+			errs = append(errs, fmt.Errorf("server[%d]: invalid publishedAt format, must be RFC3339: %v", index, err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func validateName(name string, index int) error {
@@ -125,6 +265,22 @@ func validateDescription(description string, index int) error {
 	return nil
 }
 
+func validateVersion(version string, index int) error {
+	if strings.TrimSpace(version) == "" {
+		return fmt.Errorf("server[%d]: 'version' is required and cannot be empty", index)
+	}
+
+	if version == VersionLatest {
+		return fmt.Errorf("server[%d]: 'version' cannot be '%s', it is reserved for resolving the latest version", index, VersionLatest)
+	}
+
+	if strings.ContainsAny(version, "/ ") {
+		return fmt.Errorf("server[%d]: 'version' cannot contain slashes or spaces", index)
+	}
+
+	return nil
+}
+
 func validateStatus(status string, index int) error {
 	switch status {
 	case StatusActive, StatusDeprecated, StatusDeleted:
@@ -134,41 +290,422 @@ func validateStatus(status string, index int) error {
 	}
 }
 
-var templateVarRegex = regexp.MustCompile(`\{\{[a-zA-Z_][a-zA-Z0-9_]*\}\}`)
+// validateDeprecation checks the fields that explain why a version was
+// deprecated or deleted, which make no sense on an active version.
+func validateDeprecation(server *StaticServerData, index int) error {
+	if server.DeprecationMessage == "" && server.ReplacedBy == "" {
+		return nil
+	}
+
+	if server.Status == "" || server.Status == StatusActive {
+		return fmt.Errorf("server[%d]: 'deprecationMessage' and 'replacedBy' are only allowed when 'status' is %s or %s", index, StatusDeprecated, StatusDeleted)
+	}
+
+	var errs []error
+	if len(server.DeprecationMessage) > DeprecationMessageMaxLength {
+		errs = append(errs, fmt.Errorf("server[%d]: 'deprecationMessage' must be at most %d characters", index, DeprecationMessageMaxLength))
+	}
+	if server.ReplacedBy != "" {
+		if !serverNameRegex.MatchString(server.ReplacedBy) {
+			errs = append(errs, fmt.Errorf("server[%d]: 'replacedBy' must be a server name, such as 'io.github.org/server-name'", index))
+		} else if server.ReplacedBy == server.Name {
+			errs = append(errs, fmt.Errorf("server[%d]: 'replacedBy' cannot refer to the server itself", index))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateScope checks that a server is scoped to known environments and a
+// known audience.
+func validateScope(server *StaticServerData, index int) error {
+	var errs []error
+	if server.Environments != nil && len(server.Environments) == 0 {
+		errs = append(errs, fmt.Errorf("server[%d]: 'environments' cannot be empty, leave it out to serve the server everywhere", index))
+	}
+	for j, environment := range server.Environments {
+		if !slices.Contains(knownEnvironments, environment) {
+			errs = append(errs, fmt.Errorf("server[%d].environments[%d]: must be one of: %s", index, j, strings.Join(knownEnvironments, ", ")))
+		} else if slices.Index(server.Environments, environment) != j {
+			errs = append(errs, fmt.Errorf("server[%d].environments[%d]: duplicate environment '%s'", index, j, environment))
+		}
+	}
+	if server.Audience != "" && !slices.Contains(knownAudiences, server.Audience) {
+		errs = append(errs, fmt.Errorf("server[%d]: 'audience' must be one of: %s", index, strings.Join(knownAudiences, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	// teamSlugRegex matches GitHub team slugs, which are lowercase with
+	// hyphens for spaces.
+	teamSlugRegex     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	slackChannelRegex = regexp.MustCompile(`^#[a-z0-9][a-z0-9._-]{0,79}$`)
+)
+
+// validateOwnership checks the owner team, contact and links of a server.
+// Only local entries must have an owner, see requireOwner.
+func validateOwnership(server *StaticServerData, index int) error {
+	var errs []error
+	if server.Owner != "" && !teamSlugRegex.MatchString(server.Owner) {
+		errs = append(errs, fmt.Errorf("server[%d]: 'owner' must be a GitHub team slug in the navikt organization, such as 'team-copilot', without '@navikt/'", index))
+	}
+	if server.Contact != "" && !validContact(server.Contact) {
+		errs = append(errs, fmt.Errorf("server[%d]: 'contact' must be a Slack channel, such as '#team-copilot', or an email address", index))
+	}
+	if server.Repository != nil {
+		if err := validateHTTPSURL(server.Repository.URL); err != nil {
+			errs = append(errs, fmt.Errorf("server[%d]: 'repository.url' %v", index, err))
+		}
+	}
+	if server.Documentation != "" {
+		if err := validateHTTPSURL(server.Documentation); err != nil {
+			errs = append(errs, fmt.Errorf("server[%d]: 'documentation' %v", index, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requireOwner checks that an allowlist entry that is not deleted has an
+// owner team, so that someone answers for it. Mirrored servers come from the
+// upstream registry without one and are not checked.
+func requireOwner(server *StaticServerData, index int) error {
+	if server.Owner == "" && server.Status != StatusDeleted {
+		return fmt.Errorf("server[%d]: 'owner' is required, nobody is responsible for '%s'", index, server.Name)
+	}
+	return nil
+}
+
+// validateReview checks the risk level, data classification, review date
+// and scopes of a server. High-risk servers must say when and by whom they
+// were reviewed. Whether that review has expired depends on the time, so it
+// is only a warning, see reviewWarnings.
+func validateReview(review *Review, index int) error {
+	prefix := fmt.Sprintf("server[%d].review", index)
+	var errs []error
+
+	if !slices.Contains(knownRisks, review.Risk) {
+		errs = append(errs, fmt.Errorf("%s: 'risk' must be one of: %s", prefix, strings.Join(knownRisks, ", ")))
+	}
+	if review.DataClassification != "" && !slices.Contains(knownClassifications, review.DataClassification) {
+		errs = append(errs, fmt.Errorf("%s: 'dataClassification' must be one of: %s", prefix, strings.Join(knownClassifications, ", ")))
+	}
+
+	if review.ReviewedAt != "" {
+		reviewedAt, err := time.Parse(time.DateOnly, review.ReviewedAt)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: 'reviewedAt' must be a date, such as '2025-06-01'", prefix))
+		case reviewedAt.After(time.Now()):
+			errs = append(errs, fmt.Errorf("%s: 'reviewedAt' cannot be in the future", prefix))
+		}
+	}
+	if review.Risk == RiskHigh {
+		if review.ReviewedAt == "" {
+			errs = append(errs, fmt.Errorf("%s: 'reviewedAt' is required for %s risk servers", prefix, RiskHigh))
+		}
+		if strings.TrimSpace(review.Reviewer) == "" {
+			errs = append(errs, fmt.Errorf("%s: 'reviewer' is required for %s risk servers", prefix, RiskHigh))
+		}
+	}
+
+	for j, scope := range review.Scopes {
+		if strings.TrimSpace(scope) == "" {
+			errs = append(errs, fmt.Errorf("%s.scopes[%d]: cannot be empty", prefix, j))
+		} else if slices.Index(review.Scopes, scope) != j {
+			errs = append(errs, fmt.Errorf("%s.scopes[%d]: duplicate scope '%s'", prefix, j, scope))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validContact reports whether contact is a Slack channel or a bare email
+// address.
+func validContact(contact string) bool {
+	if strings.HasPrefix(contact, "#") {
+		return slackChannelRegex.MatchString(contact)
+	}
+	address, err := mail.ParseAddress(contact)
+	return err == nil && address.Address == contact
+}
 
 func validateTransport(transport *Transport, serverIndex, remoteIndex int) error {
+	return validateRemote(transport, fmt.Sprintf("server[%d].remotes[%d]", serverIndex, remoteIndex))
+}
+
+func validateRemote(transport *Transport, prefix string) error {
 	if strings.TrimSpace(transport.Type) == "" {
-		return fmt.Errorf("server[%d].remotes[%d]: 'type' is required and cannot be empty", serverIndex, remoteIndex)
+		return fmt.Errorf("%s: 'type' is required and cannot be empty", prefix)
 	}
 
 	switch transport.Type {
-	case TransportTypeStreamableHTTP, TransportTypeSSE, TransportTypeStdio:
+	case TransportTypeStreamableHTTP, TransportTypeSSE:
+	case TransportTypeStdio:
+		return fmt.Errorf("%s: 'type' must be one of: %s, %s (%s transport is only valid for packages)",
+			prefix, TransportTypeStreamableHTTP, TransportTypeSSE, TransportTypeStdio)
+	default:
+		return fmt.Errorf("%s: 'type' must be one of: %s, %s", prefix, TransportTypeStreamableHTTP, TransportTypeSSE)
+	}
+
+	if strings.TrimSpace(transport.URL) == "" {
+		return fmt.Errorf("%s: 'url' is required for %s transport", prefix, transport.Type)
+	}
+
+	var errs []error
+	if err := validateURL(transport.URL); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", prefix, err))
+	}
+	errs = append(errs, validateVariables(transport.URL, transport.Variables, prefix)...)
+	errs = append(errs, validateHeaders(transport.Headers, prefix)...)
+
+	return errors.Join(errs...)
+}
+
+var (
+	sha256Regex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+	// packageVarRegex matches {name} placeholders in package transport URLs,
+	// which refer to the package's arguments and environment variables, and
+	// in remote URLs and header values, which refer to their own variables.
+	packageVarRegex = regexp.MustCompile(`\{[a-zA-Z_][a-zA-Z0-9_]*\}`)
+	// headerNameRegex matches the token characters RFC 9110 allows in a
+	// header field name.
+	headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+)
+
+// validateHeaders checks the headers of a transport. Names must be valid
+// and unique regardless of case, and values follow the rules of any other
+// input.
+func validateHeaders(headers []KeyValueInput, prefix string) []error {
+	var errs []error
+	names := make(map[string]bool)
+	for i := range headers {
+		header := &headers[i]
+		location := fmt.Sprintf("%s.headers[%d]", prefix, i)
+		canonical := http.CanonicalHeaderKey(header.Name)
+		switch {
+		case strings.TrimSpace(header.Name) == "":
+			errs = append(errs, fmt.Errorf("%s: 'name' is required and cannot be empty", location))
+		case !headerNameRegex.MatchString(header.Name):
+			errs = append(errs, fmt.Errorf("%s: '%s' is not a valid header name", location, header.Name))
+		case names[canonical]:
+			errs = append(errs, fmt.Errorf("%s: duplicate header '%s'", location, header.Name))
+		}
+		names[canonical] = true
+		if err := validateInput(&header.Input); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", location, err))
+		}
+		errs = append(errs, validateVariables(header.Value, header.Variables, location)...)
+	}
+	return errs
+}
+
+// validateVariables checks that every {name} placeholder in s is defined in
+// variables, and that every variable is used and valid. Template variables
+// have been filled in before, so any {{name}} left is already reported.
+func validateVariables(s string, variables map[string]Input, prefix string) []error {
+	var errs []error
+	used := make(map[string]bool)
+	for _, match := range packageVarRegex.FindAllString(templateVarRegex.ReplaceAllString(s, ""), -1) {
+		name := match[1 : len(match)-1]
+		if _, ok := variables[name]; !ok && !used[name] {
+			errs = append(errs, fmt.Errorf("%s: variable {%s} is not defined in 'variables'", prefix, name))
+		}
+		used[name] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		if !used[name] {
+			errs = append(errs, fmt.Errorf("%s: variable '%s' is defined but not used", prefix, name))
+		}
+		variable := variables[name]
+		if err := validateInput(&variable); err != nil {
+			errs = append(errs, fmt.Errorf("%s.variables['%s']: %v", prefix, name, err))
+		}
+	}
+	return errs
+}
+
+func validatePackage(pkg *Package, serverIndex, packageIndex int) error {
+	prefix := fmt.Sprintf("server[%d].packages[%d]", serverIndex, packageIndex)
+	var errs []error
+
+	switch pkg.RegistryType {
+	case RegistryTypeNPM, RegistryTypePyPI, RegistryTypeOCI, RegistryTypeNuGet, RegistryTypeMCPB:
+	case "":
+		errs = append(errs, fmt.Errorf("%s: 'registryType' is required and cannot be empty", prefix))
 	default:
-		return fmt.Errorf("server[%d].remotes[%d]: 'type' must be one of: %s, %s, %s",
-			serverIndex, remoteIndex, TransportTypeStreamableHTTP, TransportTypeSSE, TransportTypeStdio)
+		errs = append(errs, fmt.Errorf("%s: 'registryType' must be one of: %s, %s, %s, %s, %s", prefix,
+			RegistryTypeNPM, RegistryTypePyPI, RegistryTypeOCI, RegistryTypeNuGet, RegistryTypeMCPB))
+	}
+
+	if strings.TrimSpace(pkg.Identifier) == "" {
+		errs = append(errs, fmt.Errorf("%s: 'identifier' is required and cannot be empty", prefix))
+	}
+
+	if pkg.RegistryBaseURL != "" {
+		if err := validateHTTPSURL(pkg.RegistryBaseURL); err != nil {
+			errs = append(errs, fmt.Errorf("%s: 'registryBaseUrl' %v", prefix, err))
+		}
+	}
+
+	switch pkg.RegistryType {
+	case RegistryTypeNPM, RegistryTypePyPI, RegistryTypeNuGet:
+		if strings.TrimSpace(pkg.Version) == "" {
+			errs = append(errs, fmt.Errorf("%s: 'version' is required for %s packages", prefix, pkg.RegistryType))
+		}
+	case RegistryTypeMCPB:
+		if err := validateHTTPSURL(pkg.Identifier); err != nil {
+			errs = append(errs, fmt.Errorf("%s: 'identifier' for %s packages must be a download URL: %v", prefix, pkg.RegistryType, err))
+		}
+		if pkg.FileSHA256 == "" {
+			errs = append(errs, fmt.Errorf("%s: 'fileSha256' is required for %s packages", prefix, pkg.RegistryType))
+		}
 	}
 
-	if transport.Type == TransportTypeStreamableHTTP || transport.Type == TransportTypeSSE {
-		if strings.TrimSpace(transport.URL) == "" {
-			return fmt.Errorf("server[%d].remotes[%d]: 'url' is required for %s transport", serverIndex, remoteIndex, transport.Type)
+	if pkg.Version != "" {
+		if err := validatePackageVersion(pkg.Version); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", prefix, err))
 		}
-		if err := validateURL(transport.URL); err != nil {
-			return fmt.Errorf("server[%d].remotes[%d]: %v", serverIndex, remoteIndex, err)
+	}
+
+	if pkg.FileSHA256 != "" && !sha256Regex.MatchString(pkg.FileSHA256) {
+		errs = append(errs, fmt.Errorf("%s: 'fileSha256' must be a lowercase hex-encoded SHA-256 hash", prefix))
+	}
+
+	if len(pkg.RuntimeArguments) > 0 && strings.TrimSpace(pkg.RuntimeHint) == "" {
+		errs = append(errs, fmt.Errorf("%s: 'runtimeHint' is required when 'runtimeArguments' are present", prefix))
+	}
+
+	if err := validatePackageTransport(&pkg.Transport); err != nil {
+		errs = append(errs, fmt.Errorf("%s.transport: %v", prefix, err))
+	}
+	errs = append(errs, validateHeaders(pkg.Transport.Headers, prefix+".transport")...)
+
+	for i := range pkg.RuntimeArguments {
+		if err := validateArgument(&pkg.RuntimeArguments[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s.runtimeArguments[%d]: %v", prefix, i, err))
 		}
 	}
 
+	for i := range pkg.PackageArguments {
+		if err := validateArgument(&pkg.PackageArguments[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s.packageArguments[%d]: %v", prefix, i, err))
+		}
+	}
+
+	envNames := make(map[string]bool)
+	for i := range pkg.EnvironmentVariables {
+		env := &pkg.EnvironmentVariables[i]
+		if strings.TrimSpace(env.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s.environmentVariables[%d]: 'name' is required and cannot be empty", prefix, i))
+		} else if envNames[env.Name] {
+			errs = append(errs, fmt.Errorf("%s.environmentVariables[%d]: duplicate environment variable '%s'", prefix, i, env.Name))
+		}
+		envNames[env.Name] = true
+		if err := validateInput(&env.Input); err != nil {
+			errs = append(errs, fmt.Errorf("%s.environmentVariables[%d]: %v", prefix, i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validatePackageVersion(version string) error {
+	if version == VersionLatest {
+		return fmt.Errorf("'version' must be a specific version, not '%s'", VersionLatest)
+	}
+	if strings.ContainsAny(version, "^~<>=* ") || strings.HasSuffix(version, ".x") {
+		return fmt.Errorf("'version' must be a specific version, version ranges are not allowed")
+	}
 	return nil
 }
 
-func validateURL(rawURL string) error {
-	if templateVarRegex.MatchString(rawURL) {
-		testURL := templateVarRegex.ReplaceAllString(rawURL, "placeholder")
-		if _, err := url.Parse(testURL); err != nil {
-			return fmt.Errorf("invalid url format (after template substitution): %v", err)
+func validatePackageTransport(transport *Transport) error {
+	if len(transport.Variables) > 0 {
+		return fmt.Errorf("'variables' are only allowed on remotes, package transport URLs refer to the package's arguments and environment variables")
+	}
+
+	switch transport.Type {
+	case TransportTypeStdio:
+		if len(transport.Headers) > 0 {
+			return fmt.Errorf("'headers' are only allowed for %s and %s transport", TransportTypeStreamableHTTP, TransportTypeSSE)
 		}
 		return nil
+	case TransportTypeStreamableHTTP, TransportTypeSSE:
+	case "":
+		return fmt.Errorf("'type' is required and cannot be empty")
+	default:
+		return fmt.Errorf("'type' must be one of: %s, %s, %s", TransportTypeStdio, TransportTypeStreamableHTTP, TransportTypeSSE)
+	}
+
+	if strings.TrimSpace(transport.URL) == "" {
+		return fmt.Errorf("'url' is required for %s transport", transport.Type)
+	}
+
+	testURL := packageVarRegex.ReplaceAllString(transport.URL, "1")
+	if _, err := url.Parse(testURL); err != nil {
+		return fmt.Errorf("invalid url format: %v", err)
 	}
-	if _, err := url.Parse(rawURL); err != nil {
+
+	return nil
+}
+
+func validateArgument(arg *Argument) error {
+	switch arg.Type {
+	case ArgumentTypeNamed:
+		if strings.TrimSpace(arg.Name) == "" {
+			return fmt.Errorf("'name' is required for %s arguments", ArgumentTypeNamed)
+		}
+	case ArgumentTypePositional:
+		if arg.ValueHint == "" && arg.Value == "" {
+			return fmt.Errorf("'valueHint' or 'value' is required for %s arguments", ArgumentTypePositional)
+		}
+	case "":
+		return fmt.Errorf("'type' is required and cannot be empty")
+	default:
+		return fmt.Errorf("'type' must be one of: %s, %s", ArgumentTypePositional, ArgumentTypeNamed)
+	}
+
+	return validateInput(&arg.Input)
+}
+
+func validateInput(input *Input) error {
+	switch input.Format {
+	case "", InputFormatString, InputFormatNumber, InputFormatBoolean, InputFormatFilepath:
+	default:
+		return fmt.Errorf("'format' must be one of: %s, %s, %s, %s",
+			InputFormatString, InputFormatNumber, InputFormatBoolean, InputFormatFilepath)
+	}
+
+	if input.IsSecret && (input.Value != "" || input.Default != "") {
+		return fmt.Errorf("secret inputs cannot have a 'value' or 'default' in the registry")
+	}
+
+	if input.Default != "" && len(input.Choices) > 0 && !slices.Contains(input.Choices, input.Default) {
+		return fmt.Errorf("'default' must be one of the listed 'choices'")
+	}
+
+	return nil
+}
+
+func validateHTTPSURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url format: %v", err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("must be an absolute https URL")
+	}
+	return nil
+}
+
+// validateURL checks that rawURL parses once its {{name}} templates and
+// {name} variables are filled in.
+func validateURL(rawURL string) error {
+	testURL := templateVarRegex.ReplaceAllString(rawURL, "placeholder")
+	testURL = packageVarRegex.ReplaceAllString(testURL, "placeholder")
+	if _, err := url.Parse(testURL); err != nil {
+		if testURL != rawURL {
+			return fmt.Errorf("invalid url format (after template substitution): %v", err)
+		}
 		return fmt.Errorf("invalid url format: %v", err)
 	}
 	return nil
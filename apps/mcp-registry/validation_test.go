@@ -3,10 +3,11 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateAllowListFile(t *testing.T) {
-	err := validateAllowListFile()
+	_, err := validateAllowListFile("allowlist.json", testConfig())
 	if err != nil {
 		t.Fatalf("allowlist.json validation failed: %v", err)
 	}
@@ -154,6 +155,52 @@ func TestValidateRegistry_RequiredFields(t *testing.T) {
 			expectError: true,
 			errorMsg:    "duplicate server name",
 		},
+		{
+			name: "multiple versions of the same server",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description 1",
+						Version:     "1.0.0",
+					},
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description 2",
+						Version:     "2.0.0",
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "reserved version latest",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "latest",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'version' cannot be 'latest'",
+		},
+		{
+			name: "version with slash",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "1.0.0/beta",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'version' cannot contain slashes",
+		},
 		{
 			name: "invalid publishedAt format",
 			data: &StaticRegistryData{
@@ -276,6 +323,160 @@ func TestValidateRegistry_RequiredFields(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "deprecation message on deprecated server",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:               "io.github.test/server",
+						Description:        "Test Description",
+						Version:            "1.0.0",
+						Status:             StatusDeprecated,
+						DeprecationMessage: "Use the new server instead",
+						ReplacedBy:         "io.github.test/new-server",
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "deprecation message on active server",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:               "io.github.test/server",
+						Description:        "Test Description",
+						Version:            "1.0.0",
+						DeprecationMessage: "Use the new server instead",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "only allowed when 'status' is deprecated or deleted",
+		},
+		{
+			name: "deprecation message too long",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:               "io.github.test/server",
+						Description:        "Test Description",
+						Version:            "1.0.0",
+						Status:             StatusDeleted,
+						DeprecationMessage: strings.Repeat("a", DeprecationMessageMaxLength+1),
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'deprecationMessage' must be at most 500 characters",
+		},
+		{
+			name: "replacedBy not a server name",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "1.0.0",
+						Status:      StatusDeprecated,
+						ReplacedBy:  "new-server",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'replacedBy' must be a server name",
+		},
+		{
+			name: "replacedBy refers to itself",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "1.0.0",
+						Status:      StatusDeprecated,
+						ReplacedBy:  "io.github.test/server",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "cannot refer to the server itself",
+		},
+		{
+			name: "scoped to environments and audience",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:         "io.github.test/server",
+						Description:  "Test Description",
+						Version:      "1.0.0",
+						Environments: []string{EnvironmentDev, EnvironmentProd},
+						Audience:     AudienceInternal,
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown environment",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:         "io.github.test/server",
+						Description:  "Test Description",
+						Version:      "1.0.0",
+						Environments: []string{EnvironmentDev, "staging"},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "server[0].environments[1]: must be one of: dev, prod",
+		},
+		{
+			name: "duplicate environment",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:         "io.github.test/server",
+						Description:  "Test Description",
+						Version:      "1.0.0",
+						Environments: []string{EnvironmentDev, EnvironmentDev},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "duplicate environment 'dev'",
+		},
+		{
+			name: "empty environments",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:         "io.github.test/server",
+						Description:  "Test Description",
+						Version:      "1.0.0",
+						Environments: []string{},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'environments' cannot be empty",
+		},
+		{
+			name: "unknown audience",
+			data: &StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "1.0.0",
+						Audience:    "public",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "'audience' must be one of: internal, external",
+		},
 		{
 			name: "description too long",
 			data: &StaticRegistryData{
@@ -304,7 +505,7 @@ func TestValidateRegistry_RequiredFields(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name: "stdio transport without url is valid",
+			name: "stdio transport in remotes is rejected",
 			data: &StaticRegistryData{
 				Servers: []StaticServerData{
 					{
@@ -317,7 +518,8 @@ func TestValidateRegistry_RequiredFields(t *testing.T) {
 					},
 				},
 			},
-			expectError: false,
+			expectError: true,
+			errorMsg:    "stdio transport is only valid for packages",
 		},
 	}
 
@@ -338,3 +540,447 @@ func TestValidateRegistry_RequiredFields(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRegistry_Packages(t *testing.T) {
+	validPackage := func() Package {
+		return Package{
+			RegistryType: RegistryTypeNPM,
+			Identifier:   "@navikt/kotlin-mcp",
+			Version:      "1.2.3",
+			RuntimeHint:  "npx",
+			Transport:    Transport{Type: TransportTypeStdio},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(p *Package)
+		errorMsg string
+	}{
+		{
+			name:   "valid npm package",
+			modify: func(_ *Package) {},
+		},
+		{
+			name: "valid oci package without version",
+			modify: func(p *Package) {
+				p.RegistryType = RegistryTypeOCI
+				p.Identifier = "ghcr.io/navikt/gradle-mcp:1.0.0"
+				p.Version = ""
+				p.RuntimeHint = "docker"
+				p.RuntimeArguments = []Argument{{Type: ArgumentTypeNamed, Name: "--rm"}}
+			},
+		},
+		{
+			name: "valid mcpb package",
+			modify: func(p *Package) {
+				p.RegistryType = RegistryTypeMCPB
+				p.Identifier = "https://github.com/navikt/mcp/releases/download/v1.0.0/server.mcpb"
+				p.FileSHA256 = "fe333e598595000ae021bd27117db32ec69af6987f507ba7a63c90638ff633ce"
+			},
+		},
+		{
+			name: "valid streamable-http package transport with variables",
+			modify: func(p *Package) {
+				p.Transport = Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:{port}/mcp"}
+				p.PackageArguments = []Argument{{Type: ArgumentTypeNamed, Name: "--port", Input: Input{Default: "8080"}}}
+			},
+		},
+		{
+			name: "valid environment variables",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{
+					{Name: "GITHUB_TOKEN", Input: Input{IsRequired: true, IsSecret: true}},
+					{Name: "LOG_LEVEL", Input: Input{Default: "info", Choices: []string{"debug", "info"}}},
+				}
+			},
+		},
+		{
+			name:     "missing registryType",
+			modify:   func(p *Package) { p.RegistryType = "" },
+			errorMsg: "packages[0]: 'registryType' is required",
+		},
+		{
+			name:     "unknown registryType",
+			modify:   func(p *Package) { p.RegistryType = "maven" },
+			errorMsg: "'registryType' must be one of",
+		},
+		{
+			name:     "missing identifier",
+			modify:   func(p *Package) { p.Identifier = "" },
+			errorMsg: "'identifier' is required",
+		},
+		{
+			name:     "npm package without version",
+			modify:   func(p *Package) { p.Version = "" },
+			errorMsg: "'version' is required for npm packages",
+		},
+		{
+			name:     "version latest",
+			modify:   func(p *Package) { p.Version = "latest" },
+			errorMsg: "must be a specific version",
+		},
+		{
+			name:     "version range",
+			modify:   func(p *Package) { p.Version = "^1.2.3" },
+			errorMsg: "version ranges are not allowed",
+		},
+		{
+			name:     "wildcard version",
+			modify:   func(p *Package) { p.Version = "1.x" },
+			errorMsg: "version ranges are not allowed",
+		},
+		{
+			name: "mcpb without hash",
+			modify: func(p *Package) {
+				p.RegistryType = RegistryTypeMCPB
+				p.Identifier = "https://example.com/server.mcpb"
+			},
+			errorMsg: "'fileSha256' is required for mcpb packages",
+		},
+		{
+			name: "mcpb with non-url identifier",
+			modify: func(p *Package) {
+				p.RegistryType = RegistryTypeMCPB
+				p.FileSHA256 = "fe333e598595000ae021bd27117db32ec69af6987f507ba7a63c90638ff633ce"
+			},
+			errorMsg: "must be a download URL",
+		},
+		{
+			name:     "invalid hash",
+			modify:   func(p *Package) { p.FileSHA256 = "ABC" },
+			errorMsg: "'fileSha256' must be a lowercase hex-encoded SHA-256 hash",
+		},
+		{
+			name:     "plain http registryBaseUrl",
+			modify:   func(p *Package) { p.RegistryBaseURL = "http://registry.npmjs.org" },
+			errorMsg: "'registryBaseUrl' must be an absolute https URL",
+		},
+		{
+			name: "runtime arguments without runtimeHint",
+			modify: func(p *Package) {
+				p.RuntimeHint = ""
+				p.RuntimeArguments = []Argument{{Type: ArgumentTypeNamed, Name: "-y"}}
+			},
+			errorMsg: "'runtimeHint' is required",
+		},
+		{
+			name:     "missing transport",
+			modify:   func(p *Package) { p.Transport = Transport{} },
+			errorMsg: "packages[0].transport: 'type' is required",
+		},
+		{
+			name:     "http transport without url",
+			modify:   func(p *Package) { p.Transport = Transport{Type: TransportTypeSSE} },
+			errorMsg: "'url' is required for sse transport",
+		},
+		{
+			name:     "named argument without name",
+			modify:   func(p *Package) { p.PackageArguments = []Argument{{Type: ArgumentTypeNamed}} },
+			errorMsg: "packageArguments[0]: 'name' is required",
+		},
+		{
+			name:     "positional argument without value or hint",
+			modify:   func(p *Package) { p.PackageArguments = []Argument{{Type: ArgumentTypePositional}} },
+			errorMsg: "'valueHint' or 'value' is required",
+		},
+		{
+			name:     "unknown argument type",
+			modify:   func(p *Package) { p.PackageArguments = []Argument{{Type: "flag", Name: "--x"}} },
+			errorMsg: "'type' must be one of: positional, named",
+		},
+		{
+			name: "environment variable without name",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{{Input: Input{Description: "unnamed"}}}
+			},
+			errorMsg: "environmentVariables[0]: 'name' is required",
+		},
+		{
+			name: "duplicate environment variable",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{{Name: "TOKEN"}, {Name: "TOKEN"}}
+			},
+			errorMsg: "duplicate environment variable 'TOKEN'",
+		},
+		{
+			name: "secret with default",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{{Name: "TOKEN", Input: Input{IsSecret: true, Default: "hunter2"}}}
+			},
+			errorMsg: "secret inputs cannot have a 'value' or 'default'",
+		},
+		{
+			name: "invalid input format",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{{Name: "PORT", Input: Input{Format: "integer"}}}
+			},
+			errorMsg: "'format' must be one of",
+		},
+		{
+			name: "default outside choices",
+			modify: func(p *Package) {
+				p.EnvironmentVariables = []KeyValueInput{{Name: "MODE", Input: Input{Default: "c", Choices: []string{"a", "b"}}}}
+			},
+			errorMsg: "'default' must be one of the listed 'choices'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := validPackage()
+			tt.modify(&pkg)
+
+			err := validateRegistry(&StaticRegistryData{
+				Servers: []StaticServerData{
+					{
+						Name:        "io.github.test/server",
+						Description: "Test Description",
+						Version:     "1.0.0",
+						Packages:    []Package{pkg},
+					},
+				},
+			})
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateRemote_HeadersAndVariables(t *testing.T) {
+	team := KeyValueInput{Name: "X-Nav-Team", Input: Input{Description: "Your team", IsRequired: true}}
+	tests := []struct {
+		name      string
+		transport Transport
+		errorMsg  string
+	}{
+		{"headers", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{
+			team,
+			{Name: "Authorization", Input: Input{Value: "Bearer {token}"}, Variables: map[string]Input{"token": {IsRequired: true, IsSecret: true}}},
+		}}, ""},
+		{"url variables", Transport{Type: TransportTypeSSE, URL: "https://{tenant}.example.com/{env}/sse", Variables: map[string]Input{
+			"tenant": {IsRequired: true},
+			"env":    {Default: "prod", Choices: []string{"dev", "prod"}},
+		}}, ""},
+		{"empty header name", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{{Name: " "}}},
+			"remotes[0].headers[0]: 'name' is required"},
+		{"invalid header name", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{{Name: "X Nav Team"}}},
+			"'X Nav Team' is not a valid header name"},
+		{"duplicate header", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{team, {Name: "x-nav-team"}}},
+			"remotes[0].headers[1]: duplicate header 'x-nav-team'"},
+		{"secret header with default", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{
+			{Name: "X-Api-Key", Input: Input{IsSecret: true, Default: "changeme"}},
+		}}, "secret inputs cannot have a 'value' or 'default'"},
+		{"secret header variable with default", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{
+			{Name: "Authorization", Input: Input{Value: "Bearer {token}"}, Variables: map[string]Input{"token": {IsSecret: true, Default: "changeme"}}},
+		}}, "headers[0].variables['token']: secret inputs cannot have a 'value' or 'default'"},
+		{"undefined header variable", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Headers: []KeyValueInput{
+			{Name: "Authorization", Input: Input{Value: "Bearer {token}"}},
+		}}, "headers[0]: variable {token} is not defined in 'variables'"},
+		{"undefined url variable", Transport{Type: TransportTypeStreamableHTTP, URL: "https://{tenant}.example.com/mcp"},
+			"remotes[0]: variable {tenant} is not defined in 'variables'"},
+		{"unused url variable", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/mcp", Variables: map[string]Input{"tenant": {}}},
+			"remotes[0]: variable 'tenant' is defined but not used"},
+		{"secret url variable with value", Transport{Type: TransportTypeStreamableHTTP, URL: "https://example.com/{key}/mcp", Variables: map[string]Input{
+			"key": {IsSecret: true, Value: "abc"},
+		}}, "remotes[0].variables['key']: secret inputs cannot have a 'value' or 'default'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransport(&tt.transport, 0, 0)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidatePackage_TransportHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport Transport
+		errorMsg  string
+	}{
+		{"http headers", Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:{port}/mcp", Headers: []KeyValueInput{{Name: "X-Nav-Team", Input: Input{IsRequired: true}}}}, ""},
+		{"invalid header name", Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:8080/mcp", Headers: []KeyValueInput{{Name: "X-Nav:Team"}}},
+			"packages[0].transport.headers[0]: 'X-Nav:Team' is not a valid header name"},
+		{"stdio headers", Transport{Type: TransportTypeStdio, Headers: []KeyValueInput{{Name: "X-Nav-Team"}}},
+			"'headers' are only allowed for streamable-http and sse transport"},
+		{"variables", Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:{port}/mcp", Variables: map[string]Input{"port": {}}},
+			"'variables' are only allowed on remotes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := Package{RegistryType: RegistryTypeNPM, Identifier: "@navikt/test", Version: "1.0.0", Transport: tt.transport}
+			err := validatePackage(&pkg, 0, 0)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestRegistryWarnings_Deprecation(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   StaticServerData
+		expected string
+	}{
+		{"deprecated with message", StaticServerData{Status: StatusDeprecated, DeprecationMessage: "Use v2", ReplacedBy: "io.github.test/other"}, ""},
+		{"deprecated without message", StaticServerData{Status: StatusDeprecated}, "without a 'deprecationMessage'"},
+		{"deleted without message", StaticServerData{Status: StatusDeleted}, ""},
+		{"replacement not in allowlist", StaticServerData{Status: StatusDeleted, ReplacedBy: "io.github.test/missing"}, "which is not in the allowlist"},
+		{"replacement mirrored", StaticServerData{Status: StatusDeleted, ReplacedBy: "com.atlassian/rovo"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Name = "io.github.test/server"
+			tt.server.PublishedAt = "2025-01-01T00:00:00Z"
+			tt.server.Owner = "team-test"
+			warnings := registryWarnings(&StaticRegistryData{
+				Servers: []StaticServerData{tt.server, {Name: "io.github.test/other", PublishedAt: "2025-01-01T00:00:00Z", Owner: "team-test"}},
+				Mirror:  &MirrorConfig{Include: []string{"com.atlassian/*"}},
+			})
+
+			if tt.expected == "" {
+				if len(warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), tt.expected) {
+				t.Errorf("expected warning containing %q, got %v", tt.expected, warnings)
+			}
+		})
+	}
+}
+
+func TestValidateOwnership(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   StaticServerData
+		errorMsg string
+	}{
+		{"no ownership", StaticServerData{}, ""},
+		{"complete", StaticServerData{
+			Owner:         "team-copilot",
+			Contact:       "#team-copilot",
+			Repository:    &Repository{URL: "https://github.com/navikt/copilot", Source: "github"},
+			Documentation: "https://docs.nav.no/copilot",
+		}, ""},
+		{"email contact", StaticServerData{Owner: "team-copilot", Contact: "copilot@nav.no"}, ""},
+		{"owner with organization", StaticServerData{Owner: "@navikt/team-copilot"}, "'owner' must be a GitHub team slug"},
+		{"owner with uppercase", StaticServerData{Owner: "Team-Copilot"}, "'owner' must be a GitHub team slug"},
+		{"slack channel with spaces", StaticServerData{Contact: "#team copilot"}, "'contact' must be a Slack channel"},
+		{"named email contact", StaticServerData{Contact: "Copilot <copilot@nav.no>"}, "'contact' must be a Slack channel"},
+		{"plain text contact", StaticServerData{Contact: "ask around"}, "'contact' must be a Slack channel"},
+		{"http repository", StaticServerData{Repository: &Repository{URL: "http://github.com/navikt/copilot", Source: "github"}}, "'repository.url' must be an absolute https URL"},
+		{"relative documentation", StaticServerData{Documentation: "/docs"}, "'documentation' must be an absolute https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOwnership(&tt.server, 0)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   StaticServerData
+		expected string
+	}{
+		{"owned", StaticServerData{Owner: "team-copilot"}, ""},
+		{"without owner", StaticServerData{}, "'owner' is required"},
+		{"deprecated without owner", StaticServerData{Status: StatusDeprecated}, "'owner' is required"},
+		{"deleted without owner", StaticServerData{Status: StatusDeleted}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Name = "io.github.test/server"
+			err := requireOwner(&tt.server, 0)
+
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name     string
+		review   Review
+		errorMsg string
+	}{
+		{"low risk", Review{Risk: RiskLow}, ""},
+		{"complete", Review{Risk: RiskHigh, DataClassification: ClassificationConfidential, ReviewedAt: "2025-01-01", Reviewer: "appsec", Scopes: []string{"repo:read", "issues:write"}}, ""},
+		{"missing risk", Review{}, "'risk' must be one of: low, medium, high"},
+		{"unknown classification", Review{Risk: RiskLow, DataClassification: "secret"}, "'dataClassification' must be one of"},
+		{"invalid date", Review{Risk: RiskLow, ReviewedAt: "01.01.2025"}, "'reviewedAt' must be a date"},
+		{"future date", Review{Risk: RiskLow, ReviewedAt: time.Now().AddDate(1, 0, 0).Format(time.DateOnly)}, "cannot be in the future"},
+		{"high risk without date", Review{Risk: RiskHigh, Reviewer: "appsec"}, "'reviewedAt' is required for high risk servers"},
+		{"high risk without reviewer", Review{Risk: RiskHigh, ReviewedAt: "2025-01-01"}, "'reviewer' is required for high risk servers"},
+		{"empty scope", Review{Risk: RiskLow, Scopes: []string{" "}}, "scopes[0]: cannot be empty"},
+		{"duplicate scope", Review{Risk: RiskLow, Scopes: []string{"repo:read", "repo:read"}}, "duplicate scope 'repo:read'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReview(&tt.review, 0)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// variableEnvPrefix marks environment variables that define template
+// variables. REGISTRY_VAR_CLUSTER=dev-gcp defines {{cluster}}.
+const variableEnvPrefix = "REGISTRY_VAR_"
+
+var (
+	templateVarRegex  = regexp.MustCompile(`\{\{([a-zA-Z_][a-zA-Z0-9_]*)\}\}`)
+	variableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// loadVariables reads template variables from an optional JSON file holding
+// an object of names to values, then from REGISTRY_VAR_* entries in environ,
+// which take precedence. Names from the environment are lowercased.
+func loadVariables(file string, environ []string) (map[string]string, error) {
+	variables := make(map[string]string)
+
+	if file != "" {
+		if err := readVariablesFile(file, variables); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, variableEnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, variableEnvPrefix))
+		if variableNameRegex.MatchString(name) {
+			variables[name] = value
+		}
+	}
+
+	return variables, nil
+}
+
+func readVariablesFile(file string, variables map[string]string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("cannot read template variables file: %v", err)
+	}
+
+	var fromFile map[string]string
+	if err := json.Unmarshal(data, &fromFile); err != nil {
+		return fmt.Errorf("template variables file must be a JSON object of string values: %v", err)
+	}
+
+	for name, value := range fromFile {
+		if !variableNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid template variable name '%s' in %s", name, file)
+		}
+		variables[name] = value
+	}
+
+	return nil
+}
+
+// templateVariables returns every variable available to the allowlist. The
+// domain variables always come from DOMAIN_INTERNAL and DOMAIN_EXTERNAL.
+func (c *Config) templateVariables() map[string]string {
+	variables := make(map[string]string, len(c.Variables)+2)
+	maps.Copy(variables, c.Variables)
+	variables["domain_internal"] = c.DomainInternal
+	variables["domain_external"] = c.DomainExternal
+	return variables
+}
+
+// variableNames returns the names of the configured variables in sorted order.
+func (c *Config) variableNames() []string {
+	return slices.Sorted(maps.Keys(c.templateVariables()))
+}
+
+// substituteTemplate replaces every {{name}} in s that has a value and
+// returns the names that had none, which are left in place.
+func substituteTemplate(s string, variables map[string]string, used map[string]bool) (string, []string) {
+	var missing []string
+	result := templateVarRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := templateVarRegex.FindStringSubmatch(match)[1]
+		value, ok := variables[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		used[name] = true
+		return value
+	})
+	return result, missing
+}
+
+// substituteServerVariables fills in template variables in the transports of
+// every server and mirror override: their URLs, URL variables and headers.
+// It works on the parsed allowlist, so values are never interpreted as JSON.
+// It returns an error for every variable without a value and records the
+// variables that were used.
+func substituteServerVariables(data *StaticRegistryData, variables map[string]string, used map[string]bool) []error {
+	var errs []error
+
+	substitute := func(field *string, location string) {
+		var missing []string
+		*field, missing = substituteTemplate(*field, variables, used)
+		for _, name := range missing {
+			errs = append(errs, fmt.Errorf("%s: template variable {{%s}} has no value, set %s%s or define it in the variables file",
+				location, name, variableEnvPrefix, strings.ToUpper(name)))
+		}
+	}
+
+	substituteInputs := func(inputs map[string]Input, location string) {
+		for _, name := range slices.Sorted(maps.Keys(inputs)) {
+			input := inputs[name]
+			substitute(&input.Value, fmt.Sprintf("%s.variables['%s']", location, name))
+			substitute(&input.Default, fmt.Sprintf("%s.variables['%s']", location, name))
+			inputs[name] = input
+		}
+	}
+
+	substituteTransport := func(transport *Transport, location string) {
+		substitute(&transport.URL, location)
+		substituteInputs(transport.Variables, location)
+		for k := range transport.Headers {
+			header := &transport.Headers[k]
+			headerLocation := fmt.Sprintf("%s.headers[%d]", location, k)
+			substitute(&header.Value, headerLocation)
+			substitute(&header.Default, headerLocation)
+			substituteInputs(header.Variables, headerLocation)
+		}
+	}
+
+	for i := range data.Servers {
+		server := &data.Servers[i]
+		for j := range server.Remotes {
+			substituteTransport(&server.Remotes[j], fmt.Sprintf("server[%d].remotes[%d]", i, j))
+		}
+		for j := range server.Packages {
+			substituteTransport(&server.Packages[j].Transport, fmt.Sprintf("server[%d].packages[%d].transport", i, j))
+		}
+	}
+
+	if data.Mirror != nil {
+		for _, name := range slices.Sorted(maps.Keys(data.Mirror.Overrides)) {
+			remotes := data.Mirror.Overrides[name].Remotes
+			for j := range remotes {
+				substituteTransport(&remotes[j], fmt.Sprintf("mirror.overrides['%s'].remotes[%d]", name, j))
+			}
+		}
+	}
+
+	return errs
+}
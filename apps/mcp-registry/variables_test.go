@@ -0,0 +1,220 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadVariables(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "variables.json")
+	if err := os.WriteFile(file, []byte(`{"cluster": "dev-gcp", "team_ingress": "intern.dev.nav.no"}`), 0o644); err != nil {
+		t.Fatalf("failed to write variables file: %v", err)
+	}
+
+	variables, err := loadVariables(file, []string{
+		"REGISTRY_VAR_CLUSTER=prod-gcp",
+		"REGISTRY_VAR_ENVIRONMENT=prod",
+		"REGISTRY_VAR_=ignored",
+		"REGISTRY_VAR_NOT-VALID=ignored",
+		"PATH=/usr/bin",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"cluster":      "prod-gcp",
+		"environment":  "prod",
+		"team_ingress": "intern.dev.nav.no",
+	}
+	if len(variables) != len(expected) {
+		t.Errorf("expected %d variables, got %v", len(expected), variables)
+	}
+	for name, value := range expected {
+		if variables[name] != value {
+			t.Errorf("expected %s=%q, got %q", name, value, variables[name])
+		}
+	}
+}
+
+func TestLoadVariables_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{"not an object", `["cluster"]`, "must be a JSON object of string values"},
+		{"non-string value", `{"port": 8080}`, "must be a JSON object of string values"},
+		{"invalid name", `{"team-ingress": "x"}`, "invalid template variable name 'team-ingress'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write variables file: %v", err)
+			}
+
+			_, err := loadVariables(file, []string{"REGISTRY_VAR_CLUSTER=dev-gcp"})
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+
+	if _, err := loadVariables(filepath.Join(dir, "missing.json"), nil); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestConfig_TemplateVariables(t *testing.T) {
+	config := testConfig()
+	config.Variables = map[string]string{"cluster": "dev-gcp", "domain_internal": "ignored"}
+
+	variables := config.templateVariables()
+
+	if variables["cluster"] != "dev-gcp" {
+		t.Errorf("expected cluster variable, got %v", variables)
+	}
+	if variables["domain_internal"] != config.DomainInternal {
+		t.Errorf("expected domain_internal to come from DOMAIN_INTERNAL, got %q", variables["domain_internal"])
+	}
+	if variables["domain_external"] != config.DomainExternal {
+		t.Errorf("expected domain_external to come from DOMAIN_EXTERNAL, got %q", variables["domain_external"])
+	}
+}
+
+func TestSubstituteServerVariables(t *testing.T) {
+	data := &StaticRegistryData{
+		Servers: []StaticServerData{
+			{
+				Name: "io.github.navikt/test",
+				Remotes: []Transport{
+					{Type: TransportTypeStreamableHTTP, URL: "https://test.{{team_ingress}}/{{cluster}}/mcp"},
+					{Type: TransportTypeSSE, URL: "https://test.{{missing}}/sse"},
+				},
+				Packages: []Package{
+					{Transport: Transport{Type: TransportTypeStreamableHTTP, URL: "http://localhost:{port}/{{cluster}}"}},
+				},
+			},
+		},
+	}
+	variables := map[string]string{"team_ingress": `intern"nav.no`, "cluster": "dev-gcp", "unused": "x"}
+	used := make(map[string]bool)
+
+	errs := substituteServerVariables(data, variables, used)
+
+	if got := data.Servers[0].Remotes[0].URL; got != `https://test.intern"nav.no/dev-gcp/mcp` {
+		t.Errorf("expected values to be substituted verbatim, got %q", got)
+	}
+	if got := data.Servers[0].Packages[0].Transport.URL; got != "http://localhost:{port}/dev-gcp" {
+		t.Errorf("expected package transport to be substituted and keep {port}, got %q", got)
+	}
+	if got := data.Servers[0].Remotes[1].URL; got != "https://test.{{missing}}/sse" {
+		t.Errorf("expected unknown variable to be left in place, got %q", got)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	expected := "server[0].remotes[1]: template variable {{missing}} has no value, set REGISTRY_VAR_MISSING"
+	if !strings.Contains(errs[0].Error(), expected) {
+		t.Errorf("expected error containing %q, got %q", expected, errs[0].Error())
+	}
+
+	if !used["team_ingress"] || !used["cluster"] || used["unused"] || used["missing"] {
+		t.Errorf("unexpected used variables: %v", used)
+	}
+}
+
+func TestSubstituteServerVariables_HeadersAndVariables(t *testing.T) {
+	data := &StaticRegistryData{
+		Servers: []StaticServerData{{
+			Name: "io.github.navikt/test",
+			Remotes: []Transport{{
+				Type:      TransportTypeStreamableHTTP,
+				URL:       "https://{tenant}.{{domain_internal}}/mcp",
+				Variables: map[string]Input{"tenant": {Default: "{{cluster}}"}},
+				Headers: []KeyValueInput{
+					{Name: "X-Nav-Cluster", Input: Input{Value: "{{cluster}}"}},
+					{Name: "X-Nav-Env", Input: Input{Value: "{env}"}, Variables: map[string]Input{"env": {Default: "{{missing}}"}}},
+				},
+			}},
+		}},
+		Mirror: &MirrorConfig{Overrides: map[string]MirrorOverride{
+			"com.example/server": {Remotes: []Transport{{
+				Type:    TransportTypeStreamableHTTP,
+				URL:     "https://example.com/mcp",
+				Headers: []KeyValueInput{{Name: "X-Nav-Cluster", Input: Input{Default: "{{cluster}}"}}},
+			}}},
+		}},
+	}
+	variables := map[string]string{"domain_internal": "intern.nav.no", "cluster": "dev-gcp"}
+
+	errs := substituteServerVariables(data, variables, make(map[string]bool))
+
+	remote := data.Servers[0].Remotes[0]
+	if remote.URL != "https://{tenant}.intern.nav.no/mcp" {
+		t.Errorf("expected template to be substituted and keep {tenant}, got %q", remote.URL)
+	}
+	if got := remote.Variables["tenant"].Default; got != "dev-gcp" {
+		t.Errorf("expected URL variable default to be substituted, got %q", got)
+	}
+	if got := remote.Headers[0].Value; got != "dev-gcp" {
+		t.Errorf("expected header value to be substituted, got %q", got)
+	}
+	if got := data.Mirror.Overrides["com.example/server"].Remotes[0].Headers[0].Default; got != "dev-gcp" {
+		t.Errorf("expected mirror override header default to be substituted, got %q", got)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	expected := "server[0].remotes[0].headers[1].variables['env']: template variable {{missing}} has no value"
+	if !strings.Contains(errs[0].Error(), expected) {
+		t.Errorf("expected error containing %q, got %q", expected, errs[0].Error())
+	}
+}
+
+func TestValidateAllowListFile_Variables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	content := `{
+  "servers": [
+    {
+      "name": "io.github.navikt/test",
+      "description": "Test server",
+      "version": "1.0.0",
+      "publishedAt": "2025-01-01T00:00:00Z",
+      "owner": "team-test",
+      "remotes": [{"type": "streamable-http", "url": "https://test.{{cluster}}.{{domain_internal}}/mcp"}]
+    }
+  ]
+}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write allowlist: %v", err)
+	}
+
+	_, err := validateAllowListFile(path, testConfig())
+	if err == nil || !strings.Contains(err.Error(), "template variable {{cluster}} has no value") {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+
+	config := testConfig()
+	config.Variables = map[string]string{"cluster": `dev"gcp`, "environment": "dev"}
+
+	report := checkAllowListFile(path, config)
+	if len(report.errors) != 0 {
+		t.Fatalf("unexpected errors: %v", report.errors)
+	}
+	if got := report.data.Servers[0].Remotes[0].URL; got != `https://test.dev"gcp.intern.dev.nav.no/mcp` {
+		t.Errorf("unexpected url %q", got)
+	}
+	if len(report.warnings) != 1 || !strings.Contains(report.warnings[0].Error(), "{{environment}} is configured but not used") {
+		t.Errorf("expected unused variable warning, got %v", report.warnings)
+	}
+}
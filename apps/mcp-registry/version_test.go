@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected int
+	}{
+		{"equal", "1.0.0", "1.0.0", 0},
+		{"major", "2.0.0", "1.9.9", 1},
+		{"minor", "1.2.0", "1.10.0", -1},
+		{"patch", "1.0.10", "1.0.9", 1},
+		{"v prefix", "v1.2.0", "1.1.0", 1},
+		{"build metadata ignored", "1.0.0+build.1", "1.0.0+build.2", 0},
+		{"release above prerelease", "1.0.0", "1.0.0-rc.1", 1},
+		{"numeric prerelease", "1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"numeric below alphanumeric", "1.0.0-1", "1.0.0-alpha", -1},
+		{"longer prerelease wins", "1.0.0-alpha.1", "1.0.0-alpha", 1},
+		{"semver above non-semver", "0.0.1", "2024-release", 1},
+		{"non-semver below semver", "snapshot", "1.0.0", -1},
+		{"non-semver string order", "b", "a", 1},
+		{"leading zero is not semver", "01.0.0", "1.0.0", -1},
+		{"two components is not semver", "1.0", "0.0.1", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := compareVersions(tt.a, tt.b); result != tt.expected {
+				t.Errorf("compareVersions(%q, %q): expected %d, got %d", tt.a, tt.b, tt.expected, result)
+			}
+		})
+	}
+}
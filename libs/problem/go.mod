@@ -0,0 +1,3 @@
+module github.com/navikt/copilot/libs/problem
+
+go 1.25
@@ -0,0 +1,90 @@
+// Package problem writes HTTP API errors as RFC 9457 problem details, so
+// clients can tell errors apart by type instead of by their text. It only
+// depends on the standard library, so every service in this repository can
+// use it.
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Type is a kind of problem. Every occurrence of a type has the same URI,
+// title and status.
+type Type struct {
+	URI    string
+	Title  string
+	Status int
+}
+
+// Status returns the type for a status with no more specific type, which is
+// about:blank titled with the status text.
+func Status(status int) Type {
+	return Type{URI: "about:blank", Title: http.StatusText(status), Status: status}
+}
+
+// New returns an occurrence of t, with detail explaining this occurrence.
+func (t Type) New(detail string) *Details {
+	return &Details{Type: t.URI, Title: t.Title, Status: t.Status, Detail: detail}
+}
+
+// Details is an RFC 9457 problem details object.
+type Details struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+
+	// Extensions are additional members, such as the name of the resource
+	// the problem is about.
+	Extensions map[string]any
+}
+
+// With adds an extension member to p and returns p.
+func (p *Details) With(key string, value any) *Details {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// MarshalJSON writes the extension members next to the standard members,
+// which take precedence.
+func (p *Details) MarshalJSON() ([]byte, error) {
+	members := maps.Clone(p.Extensions)
+	if members == nil {
+		members = make(map[string]any, 5)
+	}
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// Write writes p as the response to r. The instance defaults to the escaped path
+// of r.
+func Write(w http.ResponseWriter, r *http.Request, p *Details) {
+	if p.Instance == "" {
+		p.Instance = r.URL.EscapedPath()
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Error("Failed to encode problem details", "error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	notFound := Type{URI: "https://example.com/problems/not-found", Title: "Thing not found", Status: http.StatusNotFound}
+
+	tests := []struct {
+		name     string
+		problem  *Details
+		expected map[string]any
+	}{
+		{
+			name:    "type with extensions",
+			problem: notFound.New("No thing named foo").With("name", "foo"),
+			expected: map[string]any{
+				"type":     "https://example.com/problems/not-found",
+				"title":    "Thing not found",
+				"status":   float64(http.StatusNotFound),
+				"detail":   "No thing named foo",
+				"instance": "/things/foo",
+				"name":     "foo",
+			},
+		},
+		{
+			name:    "status",
+			problem: Status(http.StatusMethodNotAllowed).New(""),
+			expected: map[string]any{
+				"type":     "about:blank",
+				"title":    "Method Not Allowed",
+				"status":   float64(http.StatusMethodNotAllowed),
+				"instance": "/things/foo",
+			},
+		},
+		{
+			name:    "extensions do not replace standard members",
+			problem: notFound.New("").With("status", 200).With("instance", "/other"),
+			expected: map[string]any{
+				"type":     "https://example.com/problems/not-found",
+				"title":    "Thing not found",
+				"status":   float64(http.StatusNotFound),
+				"instance": "/things/foo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/things/foo?verbose=true", nil)
+			w := httptest.NewRecorder()
+
+			Write(w, req, tt.problem)
+
+			if w.Code != tt.problem.Status {
+				t.Errorf("expected status %d, got %d", tt.problem.Status, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != ContentType {
+				t.Errorf("expected Content-Type %s, got %s", ContentType, contentType)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to parse problem: %v", err)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("expected problem %v, got %v", tt.expected, body)
+			}
+		})
+	}
+}